@@ -0,0 +1,190 @@
+package gostress
+
+import (
+	"fmt"
+	"math"
+)
+
+type (
+	BaselineOptions struct {
+		// File is a path of the Result saved by the previous run which current run will be compared against
+		File string
+		// SaveFile is a path where Result of the current run will be saved, so it can be used as a baseline later
+		SaveFile string
+		// LatencyTolerance is a relative increase of the latency quantile which is not considered as regression (default 0.1)
+		LatencyTolerance float64
+		// ThroughputTolerance is a relative decrease of achieved rps which is not considered as regression (default 0.1)
+		ThroughputTolerance float64
+		// Significance is a p-value threshold of the Mann-Whitney test for latency distributions (default 0.01)
+		Significance float64
+		Quantiles    []float64
+	}
+
+	Regression struct {
+		Stage    string
+		Status   string
+		Metric   string
+		Baseline float64
+		Current  float64
+		PValue   float64
+		// Message describes regressions which can't be measured, like mismatch of the schedules
+		Message string
+	}
+)
+
+func (o BaselineOptions) withDefaults() BaselineOptions {
+	if o.LatencyTolerance == 0 {
+		o.LatencyTolerance = 0.1
+	}
+	if o.ThroughputTolerance == 0 {
+		o.ThroughputTolerance = 0.1
+	}
+	if o.Significance == 0 {
+		o.Significance = 0.01
+	}
+	if len(o.Quantiles) == 0 {
+		o.Quantiles = []float64{0.5, 0.9, 0.99}
+	}
+	return o
+}
+
+func (r Regression) String() string {
+	if r.Message != "" {
+		return fmt.Sprintf("stage %v, %v: %v", r.Stage, r.Metric, r.Message)
+	}
+	change := 0.0
+	if r.Baseline != 0 {
+		change = 100 * (r.Current - r.Baseline) / r.Baseline
+	}
+	description := fmt.Sprintf("stage %v, %v: baseline=%.6g, current=%.6g (%+.1f%%)", r.Stage, r.Metric, r.Baseline, r.Current, change)
+	if r.Status != "" {
+		description = fmt.Sprintf("stage %v, status %v, %v: baseline=%.6g, current=%.6g (%+.1f%%, p-value=%.3g)", r.Stage, r.Status, r.Metric, r.Baseline, r.Current, change, r.PValue)
+	}
+	return description
+}
+
+// MannWhitney returns one-sided p-value of the hypothesis that values from the current histogram are not
+// stochastically greater than values from the baseline histogram
+// Values from the same histogram bucket are treated as ties
+func MannWhitney(baseline, current *LatencyHistogram) float64 {
+	n1, n2 := float64(baseline.Count), float64(current.Count)
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+	buckets := len(baseline.Counts)
+	if len(current.Counts) > buckets {
+		buckets = len(current.Counts)
+	}
+	at := func(counts []int64, i int) float64 {
+		if i < len(counts) {
+			return float64(counts[i])
+		}
+		return 0
+	}
+	rankSum, seen, ties := 0.0, 0.0, 0.0
+	for i := 0; i < buckets; i++ {
+		a, b := at(baseline.Counts, i), at(current.Counts, i)
+		t := a + b
+		if t == 0 {
+			continue
+		}
+		rankSum += b * (seen + (t+1)/2)
+		seen += t
+		ties += t*t*t - t
+	}
+	n := n1 + n2
+	u := rankSum - n2*(n2+1)/2
+	sigma := math.Sqrt(n1 * n2 / 12 * ((n + 1) - ties/(n*(n-1))))
+	if sigma == 0 {
+		return 1
+	}
+	z := (u - n1*n2/2) / sigma
+	return 0.5 * math.Erfc(z/math.Sqrt2)
+}
+
+func compareStage(stage string, baseline, current StageResult, options BaselineOptions) []Regression {
+	regressions := make([]Regression, 0)
+	if current.AchievedRps < baseline.AchievedRps*(1-options.ThroughputTolerance) {
+		regressions = append(regressions, Regression{Stage: stage, Metric: "achieved_rps", Baseline: baseline.AchievedRps, Current: current.AchievedRps})
+	}
+	for _, status := range sortedKeys(baseline.Histograms) {
+		next, ok := current.Histograms[status]
+		if !ok {
+			continue
+		}
+		prev := baseline.Histograms[status]
+		pValue := MannWhitney(prev, next)
+		if pValue >= options.Significance {
+			continue
+		}
+		for _, q := range options.Quantiles {
+			was, now := prev.Quantile(q).Seconds(), next.Quantile(q).Seconds()
+			if now > was*(1+options.LatencyTolerance) {
+				regressions = append(regressions, Regression{
+					Stage:    stage,
+					Status:   status,
+					Metric:   fmt.Sprintf("p%g", math.Round(q*1e6)/1e4),
+					Baseline: was,
+					Current:  now,
+					PValue:   pValue,
+				})
+			}
+		}
+	}
+	return regressions
+}
+
+// CompareResults finds significant regressions of the current result against the baseline for every stage and for the whole run
+// Latency quantile is reported as regression only if it exceeds tolerance and Mann-Whitney test confirms the shift of distribution
+func CompareResults(baseline, current Result, options BaselineOptions) []Regression {
+	options = options.withDefaults()
+	regressions := compareStage("total", baseline.Total, current.Total, options)
+	if stagesNamed(baseline.Stages) {
+		// named stages are matched by name, so stages inserted into the schedule don't shift comparison
+		stages := make(map[string]StageResult, len(current.Stages))
+		for _, stage := range current.Stages {
+			stages[stage.Params.Name] = stage
+		}
+		for _, prev := range baseline.Stages {
+			next, ok := stages[prev.Params.Name]
+			if !ok {
+				regressions = append(regressions, Regression{Stage: prev.Params.Name, Metric: "schedule", Message: "stage is missing in the current run"})
+				continue
+			}
+			regressions = append(regressions, compareStage(prev.Params.Name, prev, next, options)...)
+		}
+		return regressions
+	}
+	if !sameSchedule(baseline.Stages, current.Stages) {
+		return append(regressions, Regression{
+			Stage:   "all",
+			Metric:  "schedule",
+			Message: fmt.Sprintf("schedules differ (%v stages in baseline, %v in the current run), stages are not compared", len(baseline.Stages), len(current.Stages)),
+		})
+	}
+	for i := range baseline.Stages {
+		regressions = append(regressions, compareStage(fmt.Sprintf("%v", i), baseline.Stages[i], current.Stages[i], options)...)
+	}
+	return regressions
+}
+
+func stagesNamed(stages []StageResult) bool {
+	for _, stage := range stages {
+		if stage.Params.Name == "" {
+			return false
+		}
+	}
+	return len(stages) > 0
+}
+
+func sameSchedule(baseline, current []StageResult) bool {
+	if len(baseline) != len(current) {
+		return false
+	}
+	for i := range baseline {
+		if baseline[i].Params != current[i].Params {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+package gostress
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"math/rand"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func randomHistogram(n int, mean time.Duration) *LatencyHistogram {
+	h := NewLatencyHistogram()
+	for i := 0; i < n; i++ {
+		h.Record(time.Duration(rand.ExpFloat64() * float64(mean)))
+	}
+	return h
+}
+
+func TestMannWhitney(t *testing.T) {
+	baseline := randomHistogram(5000, 10*time.Millisecond)
+	same := randomHistogram(5000, 10*time.Millisecond)
+	slower := randomHistogram(5000, 12*time.Millisecond)
+	assert.Greater(t, MannWhitney(baseline, same), 0.001)
+	assert.Less(t, MannWhitney(baseline, slower), 0.001)
+	assert.Greater(t, MannWhitney(slower, baseline), 0.5)
+}
+
+func TestCompareResults(t *testing.T) {
+	stage := func(rps float64, mean time.Duration) StageResult {
+		return StageResult{AchievedRps: rps, Histograms: map[string]*LatencyHistogram{"success": randomHistogram(5000, mean)}}
+	}
+	baseline := Result{Total: stage(100, 10*time.Millisecond), Stages: []StageResult{stage(100, 10*time.Millisecond)}}
+	content, err := json.Marshal(baseline)
+	require.Nil(t, err)
+	var restored Result
+	require.Nil(t, json.Unmarshal(content, &restored))
+
+	assert.Empty(t, CompareResults(restored, Result{Total: stage(98, 10*time.Millisecond), Stages: []StageResult{stage(98, 10*time.Millisecond)}}, BaselineOptions{}))
+	regressions := CompareResults(restored, Result{Total: stage(50, 20*time.Millisecond), Stages: []StageResult{stage(100, 10*time.Millisecond)}}, BaselineOptions{})
+	metrics := make([]string, 0)
+	for _, regression := range regressions {
+		assert.Equal(t, "total", regression.Stage)
+		metrics = append(metrics, regression.Metric)
+	}
+	assert.Equal(t, []string{"achieved_rps", "p50", "p90", "p99"}, metrics)
+}
+
+func TestCompareResultsSchedule(t *testing.T) {
+	stage := func(name string, rps float64) StageResult {
+		return StageResult{Params: LoadParams{Name: name, Rps: int(rps), Workers: 1, Duration: time.Minute}, AchievedRps: rps}
+	}
+	baseline := Result{Total: stage("", 100), Stages: []StageResult{stage("warmup", 10), stage("peak", 100)}}
+
+	// stage inserted into the schedule doesn't shift comparison of the named stages
+	current := Result{Total: stage("", 100), Stages: []StageResult{stage("warmup", 10), stage("ramp", 50), stage("peak", 100)}}
+	assert.Empty(t, CompareResults(baseline, current, BaselineOptions{}))
+
+	current = Result{Total: stage("", 100), Stages: []StageResult{stage("warmup", 10), stage("spike", 500)}}
+	regressions := CompareResults(baseline, current, BaselineOptions{})
+	require.Len(t, regressions, 1)
+	assert.Equal(t, "stage peak, schedule: stage is missing in the current run", regressions[0].String())
+
+	baseline = Result{Total: stage("", 100), Stages: []StageResult{stage("", 10), stage("", 100)}}
+	current = Result{Total: stage("", 100), Stages: []StageResult{stage("", 100), stage("", 10)}}
+	regressions = CompareResults(baseline, current, BaselineOptions{})
+	require.Len(t, regressions, 1)
+	assert.Equal(t, "schedule", regressions[0].Metric)
+}
+
+func TestCheckBaselineSkipsAbortedRuns(t *testing.T) {
+	logger := zaptest.NewLogger(t).Sugar()
+	baselineFile, saveFile := filepath.Join(t.TempDir(), "baseline.json"), filepath.Join(t.TempDir(), "saved.json")
+	require.Nil(t, Result{Total: StageResult{AchievedRps: 100}}.WriteFile(baselineFile))
+	failures := &cliFailures{logger: logger}
+	stress := &Stress{
+		Options: GoStressOptions{Baseline: BaselineOptions{File: baselineFile, SaveFile: saveFile}},
+		Metrics: NewMetrics(t.Name(), nil),
+		Logger:  logger,
+		T:       failures,
+	}
+	for _, result := range []Result{{Aborted: true, Total: StageResult{AchievedRps: 10}}, {Invalid: true, Total: StageResult{AchievedRps: 10}}} {
+		stress.checkBaseline(result)
+		assert.False(t, failures.Failed())
+		assert.NoFileExists(t, saveFile)
+	}
+	stress.checkBaseline(Result{Total: StageResult{AchievedRps: 10}})
+	assert.True(t, failures.Failed())
+	assert.FileExists(t, saveFile)
+}
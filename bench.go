@@ -0,0 +1,105 @@
+package gostress
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// BenchOptions configures stress run inside the go benchmark:
+//
+//	func BenchmarkService(b *testing.B) {
+//		gostress.RunBenchmark(b, gostress.BenchOptions{Concurrency: 16}, f)
+//	}
+type BenchOptions struct {
+	// Schedule runs open-loop load with the given schedule once per benchmark call and ignores b.N
+	// Make schedule longer than -benchtime (or use -benchtime=1x), otherwise go test repeats it with growing b.N
+	Schedule LoadSchedule
+	// Concurrency is a number of closed-loop workers which execute b.N requests when Schedule is empty (default GOMAXPROCS)
+	Concurrency int
+	// WorkerTimeout is a timeout of the single request (default 10s)
+	WorkerTimeout time.Duration
+}
+
+// Benchmark metrics reported by RunBenchmark, latency percentiles are computed over successful requests
+const (
+	BenchP50Metric       = "p50-ns/op"
+	BenchP99Metric       = "p99-ns/op"
+	BenchRpsMetric       = "req/s"
+	BenchErrorRateMetric = "errors/op"
+)
+
+// RunBenchmark runs f under the testing.B and reports latency percentiles, achieved rps and error rate with b.ReportMetric, so results can be compared with benchstat
+func RunBenchmark(b *testing.B, options BenchOptions, f StressFn) Result {
+	if options.WorkerTimeout == 0 {
+		options.WorkerTimeout = 10 * time.Second
+	}
+	if options.Concurrency == 0 {
+		options.Concurrency = runtime.GOMAXPROCS(0)
+	}
+	logger := zaptest.NewLogger(b, zaptest.Level(zap.WarnLevel)).Sugar()
+	metrics := NewMetrics(b.Name(), nil)
+
+	result := Result{Name: b.Name()}
+	start := metrics.Snapshot()
+	b.ResetTimer()
+	if len(options.Schedule) > 0 {
+		pool := NewWorkerPool(options.WorkerTimeout, metrics, logger, f)
+		stages, err := NewRunner(metrics).RunSchedule(context.Background(), options.Schedule, pool, logger)
+		pool.Drain(2 * options.WorkerTimeout)
+		if err != nil {
+			b.Errorf("run schedule failed: %v", err)
+		}
+		result.Stages = stages
+	} else {
+		runClosedLoop(b.N, options.Concurrency, options.WorkerTimeout, metrics, logger, f)
+	}
+	b.StopTimer()
+	end := metrics.Snapshot()
+	for i := range result.Stages {
+		result.Stages[i].Latency, result.Stages[i].Histograms = summarizeHistograms(metrics.Latency.StageSnapshot(result.Stages[i].Index))
+	}
+	result.StartTime, result.EndTime, result.Duration = start.Time, end.Time, end.Time.Sub(start.Time)
+	result.Total = end.StageResult(start)
+
+	total := result.Total
+	if latency, ok := total.Latency["success"]; ok {
+		b.ReportMetric(float64(latency.P50.Nanoseconds()), BenchP50Metric)
+		b.ReportMetric(float64(latency.P99.Nanoseconds()), BenchP99Metric)
+		if len(options.Schedule) > 0 {
+			// go test divides the whole schedule duration by b.N otherwise
+			b.ReportMetric(float64(latency.Avg.Nanoseconds()), "ns/op")
+		}
+	}
+	b.ReportMetric(total.AchievedRps, BenchRpsMetric)
+	if total.Sent > 0 {
+		b.ReportMetric(float64(total.Errors)/float64(total.Sent), BenchErrorRateMetric)
+	}
+	return result
+}
+
+// runClosedLoop executes n requests with concurrency workers, every worker sends the next request right after the previous one is finished
+func runClosedLoop(n, concurrency int, timeout time.Duration, metrics *Metrics, logger *zap.SugaredLogger, f StressFn) {
+	next := int64(-1)
+	workers := sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		workers.Add(1)
+		go func(worker Id) {
+			defer workers.Done()
+			for {
+				id := atomic.AddInt64(&next, 1)
+				if id >= int64(n) {
+					return
+				}
+				metrics.ObserveTrigger(0, true)
+				executeRequest(Id(id), worker, metrics.Stage(), nil, timeout, metrics, logger, f)
+			}
+		}(Id(i))
+	}
+	workers.Wait()
+}
@@ -0,0 +1,56 @@
+package gostress
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func BenchmarkClosedLoop(b *testing.B) {
+	RunBenchmark(b, BenchOptions{Concurrency: 4}, func(ctx RequestContext) error {
+		time.Sleep(time.Millisecond)
+		return nil
+	})
+}
+
+func TestRunBenchmarkClosedLoop(t *testing.T) {
+	var result Result
+	benchmark := testing.Benchmark(func(b *testing.B) {
+		result = RunBenchmark(b, BenchOptions{Concurrency: 4}, func(ctx RequestContext) error {
+			time.Sleep(time.Millisecond)
+			if ctx.Id%4 == 0 {
+				return fmt.Errorf("unavailable")
+			}
+			return nil
+		})
+	})
+	require.Greater(t, benchmark.N, 1)
+	assert.Equal(t, int64(benchmark.N), result.Total.Sent)
+	assert.InDelta(t, 0.25, benchmark.Extra[BenchErrorRateMetric], 0.01)
+	assert.GreaterOrEqual(t, benchmark.Extra[BenchP50Metric], float64(time.Millisecond))
+	assert.GreaterOrEqual(t, benchmark.Extra[BenchP99Metric], benchmark.Extra[BenchP50Metric])
+	assert.Equal(t, result.Total.AchievedRps, benchmark.Extra[BenchRpsMetric])
+	// every request sleeps at least 1ms, so 4 workers can't exceed 4000 req/s
+	assert.Greater(t, benchmark.Extra[BenchRpsMetric], 0.0)
+	assert.LessOrEqual(t, benchmark.Extra[BenchRpsMetric], 4/time.Millisecond.Seconds())
+}
+
+func TestRunBenchmarkSchedule(t *testing.T) {
+	var result Result
+	benchmark := testing.Benchmark(func(b *testing.B) {
+		result = RunBenchmark(b, BenchOptions{Schedule: LoadSchedule{{Rps: 100, Workers: 4, Duration: 1500 * time.Millisecond}}}, func(ctx RequestContext) error {
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		})
+	})
+	require.Len(t, result.Stages, 1)
+	assert.Equal(t, result.Total.AchievedRps, benchmark.Extra[BenchRpsMetric])
+	assert.Greater(t, benchmark.Extra[BenchRpsMetric], 0.0)
+	assert.Zero(t, benchmark.Extra[BenchErrorRateMetric])
+	// ns/op is an average latency of the request, not the whole schedule duration divided by b.N
+	assert.GreaterOrEqual(t, benchmark.Extra["ns/op"], float64(10*time.Millisecond))
+	assert.Less(t, benchmark.Extra["ns/op"], float64(result.Duration.Nanoseconds()))
+	assert.GreaterOrEqual(t, benchmark.Extra[BenchP99Metric], benchmark.Extra[BenchP50Metric])
+}
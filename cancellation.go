@@ -2,19 +2,75 @@ package gostress
 
 import (
 	"context"
+	"fmt"
+	"go.uber.org/zap"
 	"os"
 	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 )
 
+// DefaultDrainTimeout is a time given to the run after the first signal in addition to the worker and teardown timeouts
+const DefaultDrainTimeout = 30 * time.Second
+
+// shutdownSignals are sent by the terminal (Ctrl-C) and by the kubelet when the pod is deleted
+var shutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// ShutdownCtx returns context which is cancelled on the first SIGINT or SIGTERM; second signal terminates the process
+// RunLocal handles signals by itself (with the drain timeout), so ShutdownCtx is needed only to stop the code around it
 func ShutdownCtx() context.Context {
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, _ := NotifyShutdown(context.Background(), 0, zap.NewNop().Sugar())
+	return ctx
+}
+
+// NotifyShutdown returns context which is cancelled on the first SIGINT or SIGTERM, so the run can drain in-flight requests and flush reports
+// Second signal or expiration of the drainTimeout (if positive) after the first one terminates the process
+// Returned function stops listening for signals, call it when drain is finished
+func NotifyShutdown(parent context.Context, drainTimeout time.Duration, logger *zap.SugaredLogger) (context.Context, func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, shutdownSignals...)
+	ctx, stop := watchShutdown(parent, signals, drainTimeout, logger, func() { os.Exit(1) })
+	return ctx, func() {
+		signal.Stop(signals)
+		stop()
+	}
+}
+
+func watchShutdown(parent context.Context, signals <-chan os.Signal, drainTimeout time.Duration, logger *zap.SugaredLogger, exit func()) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(parent)
+	done := make(chan struct{})
 	go func() {
-		<-c
-		cancel()
+		select {
+		case received := <-signals:
+			logger.Warnf("received %v, stopping the load and draining in-flight requests (send it again to exit immediately)", received)
+			cancel(fmt.Errorf("received %v signal", received))
+		case <-done:
+			return
+		}
+		var timeout <-chan time.Time
+		if drainTimeout > 0 {
+			timer := time.NewTimer(drainTimeout)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		select {
+		case received := <-signals:
+			logger.Errorf("received %v during drain, exit immediately", received)
+			exit()
+		case <-timeout:
+			logger.Errorf("drain didn't finish within %v, exit immediately", drainTimeout)
+			exit()
+		case <-done:
+		}
 	}()
-	return ctx
+	once := sync.Once{}
+	return ctx, func() {
+		once.Do(func() {
+			close(done)
+			cancel(context.Canceled)
+		})
+	}
 }
 
 func Finished(ctx context.Context) bool {
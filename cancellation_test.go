@@ -0,0 +1,78 @@
+package gostress
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestWatchShutdown(t *testing.T) {
+	logger := zaptest.NewLogger(t).Sugar()
+	signals := make(chan os.Signal, 1)
+	exited := make(chan struct{}, 1)
+	ctx, stop := watchShutdown(context.Background(), signals, time.Minute, logger, func() { exited <- struct{}{} })
+	defer stop()
+	assert.False(t, Finished(ctx))
+
+	signals <- syscall.SIGTERM
+	<-ctx.Done()
+	assert.Equal(t, "received terminated signal", context.Cause(ctx).Error())
+	assert.Empty(t, exited)
+
+	signals <- os.Interrupt
+	select {
+	case <-exited:
+	case <-time.After(time.Second):
+		t.Fatalf("second signal must force exit")
+	}
+}
+
+func TestWatchShutdownDrainTimeout(t *testing.T) {
+	logger := zaptest.NewLogger(t).Sugar()
+	signals := make(chan os.Signal, 1)
+	exited := make(chan struct{}, 1)
+	_, stop := watchShutdown(context.Background(), signals, 100*time.Millisecond, logger, func() { exited <- struct{}{} })
+	defer stop()
+	signals <- syscall.SIGTERM
+	select {
+	case <-exited:
+	case <-time.After(time.Second):
+		t.Fatalf("drain timeout must force exit")
+	}
+
+	ctx, stop := watchShutdown(context.Background(), signals, 100*time.Millisecond, logger, func() { exited <- struct{}{} })
+	signals <- syscall.SIGTERM
+	<-ctx.Done()
+	stop()
+	time.Sleep(200 * time.Millisecond)
+	assert.Empty(t, exited, "drain finished in time, so process must not exit")
+}
+
+func TestRunLocalDrain(t *testing.T) {
+	logger := zaptest.NewLogger(t).Sugar()
+	started, finished := int32(0), int32(0)
+	options := GoStressOptions{
+		WorkerTimeout: time.Second,
+		Schedule:      LoadSchedule{{Rps: 20, Workers: 10, Duration: time.Minute}},
+	}
+	stress, shutdown := NewStress(t.Name(), options, logger, &cliFailures{logger: logger}, func(ctx RequestContext) error {
+		atomic.AddInt32(&started, 1)
+		time.Sleep(300 * time.Millisecond)
+		atomic.AddInt32(&finished, 1)
+		return nil
+	})
+	defer shutdown()
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	result := stress.RunLocal(ctx)
+	require.True(t, result.Aborted)
+	assert.Equal(t, atomic.LoadInt32(&started), atomic.LoadInt32(&finished))
+	assert.Equal(t, int64(atomic.LoadInt32(&finished)), result.Total.Latency["success"].Count)
+	assert.Empty(t, stress.Workers.Workers)
+}
@@ -0,0 +1,195 @@
+package gostress
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"go.uber.org/zap"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	cliExitOk     = 0
+	cliExitFailed = 1
+	cliExitUsage  = 2
+)
+
+// cliNoScenarios explains an empty registry: the stock cmd/gostress binary imports no scenarios on its own
+const cliNoScenarios = "no scenarios are registered in this binary: copy cmd/gostress/main.go into your module and add blank imports of the packages which call gostress.RegisterScenario\n"
+
+// cliFailures collects failures of the run in the same way as testing.T does for the test entrypoint
+type cliFailures struct {
+	lock   sync.Mutex
+	logger *zap.SugaredLogger
+	count  int
+}
+
+func (f *cliFailures) Errorf(format string, args ...any) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.count++
+	f.logger.Errorf(format, args...)
+}
+
+func (f *cliFailures) Failed() bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.count > 0
+}
+
+const cliUsage = `usage: gostress <command> [arguments]
+
+commands:
+  list                       list registered scenarios
+  run <scenario> [flags]     run scenario (see gostress run -h for flags)
+  grafana-dashboard [flags]  print grafana dashboard for the gostress metrics (see gostress grafana-dashboard -h for flags)
+`
+
+// Main runs gostress cli with scenarios registered in the binary; call it from the main of the package which imports scenarios
+func Main() {
+	os.Exit(RunCli(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// RunCli executes cli command and returns exit code of the process
+func RunCli(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		_, _ = fmt.Fprint(stderr, cliUsage)
+		return cliExitUsage
+	}
+	switch args[0] {
+	case "list":
+		return cliList(stdout, stderr)
+	case "run":
+		return cliRun(args[1:], stderr)
+	case "grafana-dashboard":
+		return cliGrafanaDashboard(args[1:], stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		_, _ = fmt.Fprint(stdout, cliUsage)
+		return cliExitOk
+	default:
+		_, _ = fmt.Fprintf(stderr, "unknown command %v\n%v", args[0], cliUsage)
+		return cliExitUsage
+	}
+}
+
+func cliList(stdout, stderr io.Writer) int {
+	if len(Scenarios()) == 0 {
+		_, _ = fmt.Fprint(stderr, cliNoScenarios)
+		return cliExitOk
+	}
+	writer := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	for _, scenario := range Scenarios() {
+		_, _ = fmt.Fprintf(writer, "%v\t%v\n", scenario.Name, scenario.Description)
+	}
+	_ = writer.Flush()
+	return cliExitOk
+}
+
+func cliGrafanaDashboard(args []string, stdout, stderr io.Writer) int {
+	options := GrafanaDashboardOptions{}
+	flags := flag.NewFlagSet("grafana-dashboard", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&options.Title, "title", "gostress", "title of the dashboard")
+	flags.StringVar(&options.Uid, "uid", "gostress", "uid of the dashboard")
+	if err := flags.Parse(args); err != nil {
+		return cliExitUsage
+	}
+	dashboard, err := GrafanaDashboard(options)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "unable to generate dashboard: %v\n", err)
+		return cliExitFailed
+	}
+	_, _ = fmt.Fprintf(stdout, "%s\n", dashboard)
+	return cliExitOk
+}
+
+func cliRun(args []string, stderr io.Writer) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		_, _ = fmt.Fprintf(stderr, "scenario name is required: gostress run <scenario> [flags]\n")
+		return cliExitUsage
+	}
+	scenario, ok := LookupScenario(args[0])
+	if !ok {
+		if len(Scenarios()) == 0 {
+			_, _ = fmt.Fprintf(stderr, "unknown scenario %v, %v", args[0], cliNoScenarios)
+			return cliExitUsage
+		}
+		_, _ = fmt.Fprintf(stderr, "unknown scenario %v, use gostress list to see registered scenarios\n", args[0])
+		return cliExitUsage
+	}
+	options := scenario.Options
+	if options.WorkerTimeout == 0 {
+		options.WorkerTimeout = 10 * time.Second
+	}
+	var schedule, resultFile string
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&schedule, "schedule", "", `schedule in the "[name=]rps:workers:duration,..." form (default is the scenario schedule)`)
+	flags.DurationVar(&options.WorkerTimeout, "worker-timeout", options.WorkerTimeout, "timeout of the single request")
+	flags.DurationVar(&options.ReportInterval, "report-interval", options.ReportInterval, "interval between reports (default 10s)")
+	flags.Func("report", `reporter in the "format[:path]" form (text, json, markdown, junit), can be repeated`, func(value string) error {
+		options.Report.Formats = append(options.Report.Formats, value)
+		return nil
+	})
+	flags.IntVar(&options.MetricsPort, "metrics-port", options.MetricsPort, "port of the prometheus metrics endpoint (default is any free port)")
+	flags.StringVar(&options.ResultsFile, "results", options.ResultsFile, "path of the time-series results file (CSV for .csv extension, JSONL otherwise)")
+	flags.StringVar(&options.HtmlReportFile, "html", options.HtmlReportFile, "path of the html report")
+	flags.StringVar(&resultFile, "result", "", "path of the JSON result of the run")
+	flags.StringVar(&options.Baseline.File, "baseline", options.Baseline.File, "path of the baseline result to compare with")
+	flags.StringVar(&options.Baseline.SaveFile, "save-baseline", options.Baseline.SaveFile, "path to save result of the run as a baseline")
+	flags.Float64Var(&options.Thresholds.MaxErrorRate, "max-error-rate", options.Thresholds.MaxErrorRate, "maximum fraction of failed requests")
+	flags.DurationVar(&options.Thresholds.MaxP50, "max-p50", options.Thresholds.MaxP50, "maximum p50 latency of successful requests")
+	flags.DurationVar(&options.Thresholds.MaxP99, "max-p99", options.Thresholds.MaxP99, "maximum p99 latency of successful requests")
+	flags.StringVar(&options.Profile, "profile", options.Profile, "profile of the run: smoke, load, stress, spike, soak or custom one of the scenario")
+	flags.IntVar(&options.Preflight.Requests, "preflight", options.Preflight.Requests, "number of sequential requests which must succeed before the load starts")
+	flags.BoolVar(&options.DryRun, "dry-run", options.DryRun, "print the planned schedule without calling the stress function")
+	flags.DurationVar(&options.DrainTimeout, "drain-timeout", options.DrainTimeout, "time given to drain in-flight requests and flush reports after the first SIGINT/SIGTERM")
+	flags.BoolVar(&options.Dashboard, "dashboard", options.Dashboard, "show live terminal dashboard")
+	flags.StringVar(&options.Grafana.Url, "grafana-url", options.Grafana.Url, "grafana url to post run events as annotations (token is read from "+GoStressGrafanaTokenEnv+" env)")
+	flags.StringVar(&options.Grafana.DashboardUid, "grafana-dashboard", options.Grafana.DashboardUid, "uid of the grafana dashboard to bind annotations")
+	if err := flags.Parse(args[1:]); err != nil {
+		return cliExitUsage
+	}
+	if schedule != "" {
+		parsed, err := ParseLoadSchedule(schedule)
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "invalid schedule: %v\n", err)
+			return cliExitUsage
+		}
+		options.Schedule = parsed
+	}
+
+	zapLogger, err := zap.NewDevelopment()
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "unable to create logger: %v\n", err)
+		return cliExitFailed
+	}
+	logger := zapLogger.Sugar()
+	defer func() { _ = logger.Sync() }()
+	failures := &cliFailures{logger: logger}
+	stress, shutdown, err := newStress(scenario.Name, options, Overrides{}, logger, failures, scenario.F)
+	defer shutdown()
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "invalid options of scenario %v: %v\n", scenario.Name, err)
+		return cliExitUsage
+	}
+	// RunLocal drains the run on the first SIGINT/SIGTERM and exits on the second one
+	result := stress.RunLocal(context.Background())
+	if resultFile != "" {
+		if err := result.WriteFile(resultFile); err != nil {
+			failures.Errorf("unable to write result: %v", err)
+		}
+	}
+	if result.Aborted {
+		failures.Errorf("stress run aborted: %v", result.AbortReason)
+	}
+	if failures.Failed() {
+		return cliExitFailed
+	}
+	return cliExitOk
+}
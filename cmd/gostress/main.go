@@ -0,0 +1,14 @@
+// Command gostress runs registered stress scenarios outside of the go test
+//
+// Scenarios are registered with gostress.RegisterScenario from init functions, so the binary must import them:
+// copy this file into your module and add blank imports of the packages with scenarios (as is, list and run only explain this)
+//
+//	gostress list
+//	gostress run <scenario> -schedule 10:1:30s,100:8:5m -max-p99 200ms -report markdown:report.md
+package main
+
+import "github.com/sivukhin/gostress"
+
+func main() {
+	gostress.Main()
+}
@@ -0,0 +1,7 @@
+//go:build !unix
+
+package gostress
+
+import "time"
+
+func processCpuTime() (time.Duration, bool) { return 0, false }
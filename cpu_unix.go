@@ -0,0 +1,17 @@
+//go:build unix
+
+package gostress
+
+import (
+	"syscall"
+	"time"
+)
+
+// processCpuTime returns total user and system cpu time consumed by the current process
+func processCpuTime() (time.Duration, bool) {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0, false
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano()), true
+}
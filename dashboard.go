@@ -0,0 +1,148 @@
+package gostress
+
+import (
+	"fmt"
+	"golang.org/x/term"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Dashboard renders live state of the stress run in the terminal
+type Dashboard struct {
+	name        string
+	schedule    LoadSchedule
+	metrics     *Metrics
+	out         io.Writer
+	startTime   time.Time
+	lines       int
+	lastSent    int64
+	lastErrors  int64
+	lastLatency *LatencyHistogram
+	latency     []time.Duration
+}
+
+const dashboardSparklineWidth = 60
+
+var sparklineTicks = []rune("▁▂▃▄▅▆▇█")
+
+// IsTerminal reports whether the file is attached to the terminal, so dashboard can be rendered into it
+func IsTerminal(file *os.File) bool { return term.IsTerminal(int(file.Fd())) }
+
+func NewDashboard(name string, schedule LoadSchedule, metrics *Metrics, out io.Writer) *Dashboard {
+	return &Dashboard{
+		name:        name,
+		schedule:    schedule,
+		metrics:     metrics,
+		out:         out,
+		startTime:   time.Now(),
+		lastLatency: NewLatencyHistogram(),
+	}
+}
+
+func sparkline(values []time.Duration) string {
+	max := time.Duration(0)
+	for _, value := range values {
+		if value > max {
+			max = value
+		}
+	}
+	line := strings.Builder{}
+	for _, value := range values {
+		i := 0
+		if max > 0 {
+			i = int(float64(value) / float64(max) * float64(len(sparklineTicks)-1))
+		}
+		line.WriteRune(sparklineTicks[i])
+	}
+	return line.String()
+}
+
+func (d *Dashboard) merged() *LatencyHistogram {
+	merged := NewLatencyHistogram()
+	for _, histogram := range d.metrics.Latency.Snapshot() {
+		merged.Merge(histogram)
+	}
+	return merged
+}
+
+// Render returns current state of the dashboard and advances its rolling windows
+func (d *Dashboard) Render(now time.Time) []string {
+	elapsed := now.Sub(d.startTime)
+	total := time.Duration(0)
+	for _, stage := range d.schedule {
+		total += stage.Duration
+	}
+	remaining := total - elapsed
+	if remaining < 0 {
+		remaining = 0
+	}
+	sent, errors := int64(metricValue(d.metrics.SentRequestCounter)), int64(metricValue(d.metrics.ErrorsCounter))
+	errorRate := 0.0
+	if sent > d.lastSent {
+		errorRate = 100 * float64(errors-d.lastErrors) / float64(sent-d.lastSent)
+	}
+	latency := d.merged()
+	interval := latency.Sub(d.lastLatency)
+	d.latency = append(d.latency, interval.Quantile(0.99))
+	if len(d.latency) > dashboardSparklineWidth {
+		d.latency = d.latency[len(d.latency)-dashboardSparklineWidth:]
+	}
+	d.lastSent, d.lastErrors, d.lastLatency = sent, errors, latency
+
+	stage := d.metrics.Stage()
+	stageDescription := stage.String()
+	if stage.Index < len(d.schedule) {
+		stageDescription = fmt.Sprintf("%v of %v %v", stageDescription, len(d.schedule), d.schedule[stage.Index].String())
+	}
+	return []string{
+		fmt.Sprintf("gostress: %v", d.name),
+		fmt.Sprintf("  stage:     %v", stageDescription),
+		fmt.Sprintf("  elapsed:   %v, remaining: %v", elapsed.Round(time.Second), remaining.Round(time.Second)),
+		fmt.Sprintf("  rps:       expected %.0f, achieved %.1f, completed %.1f", metricValue(d.metrics.ExpectedRpsGauge), metricValue(d.metrics.AchievedRpsGauge), metricValue(d.metrics.CompletedRpsGauge)),
+		fmt.Sprintf("  workers:   %.0f (expected %.0f)", metricValue(d.metrics.CurrentWorkersGauge), metricValue(d.metrics.ExpectedWorkersGauge)),
+		fmt.Sprintf("  errors:    %.2f%% (total %v of %v, skipped %.0f)", errorRate, errors, sent, metricValue(d.metrics.SkippedRequestCounter)),
+		fmt.Sprintf("  p99:       %v (p50 %v, max %v)", roundLatency(interval.Quantile(0.99)), roundLatency(interval.Quantile(0.5)), roundLatency(interval.Max)),
+		fmt.Sprintf("  p99 trend: %v", sparkline(d.latency)),
+	}
+}
+
+// Draw renders dashboard over the previously drawn one
+func (d *Dashboard) Draw(now time.Time) {
+	lines := d.Render(now)
+	frame := strings.Builder{}
+	if d.lines > 0 {
+		frame.WriteString(fmt.Sprintf("\x1b[%dA", d.lines))
+	}
+	for _, line := range lines {
+		frame.WriteString("\x1b[2K")
+		frame.WriteString(line)
+		frame.WriteString("\n")
+	}
+	d.lines = len(lines)
+	_, _ = io.WriteString(d.out, frame.String())
+}
+
+// RunDashboard redraws dashboard every second until returned function is called
+func RunDashboard(dashboard *Dashboard) func() {
+	finish, finished := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				dashboard.Draw(now)
+			case <-finish:
+				dashboard.Draw(time.Now())
+				return
+			}
+		}
+	}()
+	return func() {
+		close(finish)
+		<-finished
+	}
+}
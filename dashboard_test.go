@@ -0,0 +1,27 @@
+package gostress
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSparkline(t *testing.T) {
+	assert.Equal(t, "▁▄█", sparkline([]time.Duration{0, 5 * time.Millisecond, 10 * time.Millisecond}))
+	assert.Equal(t, "▁▁", sparkline([]time.Duration{0, 0}))
+}
+
+func TestDashboardDraw(t *testing.T) {
+	metrics := NewMetrics(t.Name(), nil)
+	out := &bytes.Buffer{}
+	dashboard := NewDashboard(t.Name(), LoadSchedule{{Name: "peak", Rps: 10, Workers: 1, Duration: time.Minute}}, metrics, out)
+	metrics.SetStage(Stage{Index: 0, Name: "peak"})
+	metrics.ObserveLatency(metrics.Stage(), "success", 10*time.Millisecond)
+	dashboard.Draw(time.Now())
+	dashboard.Draw(time.Now())
+	frames := strings.Split(out.String(), "\x1b[8A")
+	assert.Len(t, frames, 2)
+	assert.Contains(t, frames[1], "stage 0 (peak) of 1")
+}
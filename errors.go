@@ -0,0 +1,51 @@
+package gostress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrorRecorder counts errors returned by StressFn grouped by their class
+type ErrorRecorder struct {
+	lock    sync.Mutex
+	classes map[string]int64
+}
+
+func NewErrorRecorder() *ErrorRecorder { return &ErrorRecorder{classes: make(map[string]int64)} }
+
+// ClassifyError returns low-cardinality class of the error: timeouts and cancellations are detected explicitly,
+// otherwise the type of the innermost wrapped error is used
+func ClassifyError(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "timeout"
+	}
+	if errors.Is(err, context.Canceled) {
+		return "canceled"
+	}
+	for {
+		unwrapped := errors.Unwrap(err)
+		if unwrapped == nil {
+			return fmt.Sprintf("%T", err)
+		}
+		err = unwrapped
+	}
+}
+
+func (r *ErrorRecorder) Record(err error) {
+	class := ClassifyError(err)
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.classes[class]++
+}
+
+func (r *ErrorRecorder) Snapshot() map[string]int64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	snapshot := make(map[string]int64, len(r.classes))
+	for class, count := range r.classes {
+		snapshot[class] = count
+	}
+	return snapshot
+}
@@ -0,0 +1,153 @@
+package gostress
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	EventRunStart        = "run_start"
+	EventRunEnd          = "run_end"
+	EventSetup           = "setup"
+	EventTeardown        = "teardown"
+	EventPreflight       = "preflight"
+	EventStageStart      = "stage_start"
+	EventStageEnd        = "stage_end"
+	EventPoolResize      = "pool_resize"
+	EventBehindSchedule  = "behind_schedule"
+	EventThresholdBreach = "threshold_breach"
+	EventAbort           = "abort"
+	EventPod             = "pod"
+)
+
+type (
+	EventOptions struct {
+		// File is a path of the JSONL file with all events of the run
+		File string
+		// Handlers are called synchronously for every event
+		Handlers []func(event Event)
+	}
+
+	// Event is a notable moment of the run (stage change, pool resize, threshold breach, abort, pod lifecycle, etc)
+	Event struct {
+		Time       time.Time         `json:"time"`
+		Kind       string            `json:"kind"`
+		Message    string            `json:"message"`
+		Attributes map[string]string `json:"attributes,omitempty"`
+	}
+
+	// EventLog collects events of the run; all methods are safe to call on nil EventLog
+	EventLog struct {
+		lock     sync.Mutex
+		events   []Event
+		handlers []func(event Event)
+		file     *os.File
+		encoder  *json.Encoder
+	}
+)
+
+func NewEventLog() *EventLog { return &EventLog{events: make([]Event, 0)} }
+
+// Subscribe registers handler which is called for every event emitted from now on
+func (l *EventLog) Subscribe(handler func(event Event)) {
+	if l == nil {
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.handlers = append(l.handlers, handler)
+}
+
+// WriteFile starts writing of all events (including already emitted ones) into the JSONL file
+func (l *EventLog) WriteFile(path string) error {
+	if l == nil {
+		return nil
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("unable to create event log %v: %w", path, err)
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.file, l.encoder = file, json.NewEncoder(file)
+	for _, event := range l.events {
+		if err := l.encoder.Encode(event); err != nil {
+			return fmt.Errorf("unable to write event log %v: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// Close stops writing of the events into the file
+func (l *EventLog) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file, l.encoder = nil, nil
+	return err
+}
+
+// Emit records event with attributes given as key-value pairs
+func (l *EventLog) Emit(kind, message string, attributes ...string) {
+	if l == nil {
+		return
+	}
+	event := Event{Time: time.Now(), Kind: kind, Message: message}
+	if len(attributes) > 0 {
+		event.Attributes = make(map[string]string, len(attributes)/2)
+		for i := 0; i+1 < len(attributes); i += 2 {
+			event.Attributes[attributes[i]] = attributes[i+1]
+		}
+	}
+	l.lock.Lock()
+	l.events = append(l.events, event)
+	if l.encoder != nil {
+		// event log is a best-effort diagnostic, so write errors don't break the run
+		_ = l.encoder.Encode(event)
+	}
+	handlers := l.handlers
+	l.lock.Unlock()
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+// Events returns events emitted within [from, to] interval
+func (l *EventLog) Events(from, to time.Time) []Event {
+	if l == nil {
+		return nil
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	events := make([]Event, 0)
+	for _, event := range l.events {
+		if !event.Time.Before(from) && !event.Time.After(to) {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// NotableEvents filters out frequent events (pool resizes) which would clutter reports
+func NotableEvents(events []Event) []Event {
+	notable := make([]Event, 0, len(events))
+	for _, event := range events {
+		if event.Kind != EventPoolResize {
+			notable = append(notable, event)
+		}
+	}
+	return notable
+}
+
+func (e Event) String() string {
+	return fmt.Sprintf("%v %v: %v", e.Time.Format("15:04:05.000"), e.Kind, e.Message)
+}
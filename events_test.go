@@ -0,0 +1,84 @@
+package gostress
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEventLog(t *testing.T) {
+	log := NewEventLog()
+	log.Emit(EventRunStart, "run started")
+	received := make([]Event, 0)
+	log.Subscribe(func(event Event) { received = append(received, event) })
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	require.Nil(t, log.WriteFile(path))
+	log.Emit(EventPoolResize, "workers 1 -> 4", "from", "1", "to", "4")
+	log.Emit(EventAbort, "context canceled")
+	require.Nil(t, log.Close())
+	log.Emit(EventRunEnd, "run finished")
+
+	require.Len(t, received, 3)
+	assert.Equal(t, map[string]string{"from": "1", "to": "4"}, received[0].Attributes)
+	assert.Len(t, log.Events(time.Time{}, time.Now()), 4)
+	assert.Empty(t, log.Events(time.Now().Add(time.Hour), time.Now().Add(2*time.Hour)))
+	assert.Equal(t, []string{EventRunStart, EventAbort, EventRunEnd}, eventKinds(NotableEvents(log.Events(time.Time{}, time.Now()))))
+
+	file, err := os.Open(path)
+	require.Nil(t, err)
+	defer file.Close()
+	written := make([]Event, 0)
+	for scanner := bufio.NewScanner(file); scanner.Scan(); {
+		var event Event
+		require.Nil(t, json.Unmarshal(scanner.Bytes(), &event))
+		written = append(written, event)
+	}
+	assert.Equal(t, []string{EventRunStart, EventPoolResize, EventAbort}, eventKinds(written))
+}
+
+func TestEventLogNil(t *testing.T) {
+	var log *EventLog
+	log.Emit(EventRunStart, "run started")
+	log.Subscribe(func(event Event) {})
+	assert.Nil(t, log.WriteFile(filepath.Join(t.TempDir(), "events.jsonl")))
+	assert.Nil(t, log.Close())
+	assert.Nil(t, log.Events(time.Time{}, time.Now()))
+}
+
+func TestScheduleEvents(t *testing.T) {
+	metrics := NewMetrics(t.Name(), nil)
+	logger := zaptest.NewLogger(t).Sugar()
+	pool := NewWorkerPool(time.Second, metrics, logger, func(ctx RequestContext) error { return nil })
+	schedule := LoadSchedule{
+		{Name: "warmup", Rps: 10, Workers: 1, Duration: 500 * time.Millisecond},
+		{Name: "peak", Rps: 10, Workers: 4, Duration: 500 * time.Millisecond},
+	}
+	_, err := NewRunner(metrics).RunSchedule(context.Background(), schedule, pool, logger)
+	require.Nil(t, err)
+	events := metrics.Events.Events(time.Time{}, time.Now())
+	assert.Contains(t, eventKinds(events), EventPoolResize)
+	stages := make([]Event, 0)
+	for _, event := range events {
+		if event.Kind == EventStageStart || event.Kind == EventStageEnd {
+			stages = append(stages, event)
+		}
+	}
+	require.Len(t, stages, 4)
+	assert.Equal(t, []string{EventStageStart, EventStageEnd, EventStageStart, EventStageEnd}, eventKinds(stages))
+	assert.Equal(t, "peak", stages[2].Attributes["stage_name"])
+}
+
+func eventKinds(events []Event) []string {
+	kinds := make([]string, 0, len(events))
+	for _, event := range events {
+		kinds = append(kinds, event.Kind)
+	}
+	return kinds
+}
@@ -0,0 +1,240 @@
+package gostress
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protowire"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type (
+	ExportOptions struct {
+		// PushgatewayUrl enables pushing of stress metrics to the Prometheus Pushgateway
+		PushgatewayUrl string
+		// RemoteWriteUrl enables sending of stress metrics with Prometheus remote-write protocol
+		RemoteWriteUrl string
+		// Interval between exports (default 10s); metrics are always exported once more on completion
+		Interval time.Duration
+	}
+
+	// Exporter sends current state of the stress metrics to the external system
+	Exporter interface {
+		Name() string
+		Export(ctx context.Context) error
+	}
+
+	PushgatewayExporter struct {
+		pusher *push.Pusher
+		url    string
+		client *http.Client
+	}
+
+	// contextDoer attaches export context to the requests of the Pusher, which doesn't accept context in the client_golang v1.4.0
+	contextDoer struct {
+		ctx    context.Context
+		client *http.Client
+	}
+
+	RemoteWriteExporter struct {
+		Url      string
+		Gatherer prometheus.Gatherer
+		Client   *http.Client
+		// Labels are attached to every exported series
+		Labels map[string]string
+	}
+
+	remoteWriteLabel  struct{ name, value string }
+	remoteWriteSeries struct {
+		labels []remoteWriteLabel
+		value  float64
+	}
+)
+
+func NewPushgatewayExporter(url, job string, gatherer prometheus.Gatherer, grouping map[string]string) *PushgatewayExporter {
+	client := &http.Client{Timeout: 30 * time.Second}
+	pusher := push.New(url, job).Gatherer(gatherer).Client(client)
+	for _, name := range sortedKeys(grouping) {
+		pusher = pusher.Grouping(name, grouping[name])
+	}
+	return &PushgatewayExporter{pusher: pusher, url: url, client: client}
+}
+
+func (e *PushgatewayExporter) Name() string { return fmt.Sprintf("pushgateway(%v)", e.url) }
+
+// Export pushes metrics within ctx; exports are sequential, so the client of the pusher can be replaced for every call
+func (e *PushgatewayExporter) Export(ctx context.Context) error {
+	return e.pusher.Client(contextDoer{ctx: ctx, client: e.client}).Push()
+}
+
+func (d contextDoer) Do(request *http.Request) (*http.Response, error) {
+	return d.client.Do(request.WithContext(d.ctx))
+}
+
+func NewRemoteWriteExporter(url string, gatherer prometheus.Gatherer, labels map[string]string) *RemoteWriteExporter {
+	return &RemoteWriteExporter{Url: url, Gatherer: gatherer, Client: &http.Client{Timeout: 30 * time.Second}, Labels: labels}
+}
+
+func (e *RemoteWriteExporter) Name() string { return fmt.Sprintf("remote-write(%v)", e.Url) }
+
+func (e *RemoteWriteExporter) Export(ctx context.Context) error {
+	families, err := e.Gatherer.Gather()
+	if err != nil {
+		return fmt.Errorf("unable to gather metrics: %w", err)
+	}
+	body := snappyEncode(encodeWriteRequest(flattenFamilies(families, e.Labels), time.Now()))
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, e.Url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("unable to create remote-write request: %w", err)
+	}
+	request.Header.Set("Content-Encoding", "snappy")
+	request.Header.Set("Content-Type", "application/x-protobuf")
+	request.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	response, err := e.Client.Do(request)
+	if err != nil {
+		return fmt.Errorf("remote-write request failed: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("remote-write request failed with status %v: %s", response.StatusCode, message)
+	}
+	return nil
+}
+
+// flattenFamilies converts gathered metric families into plain series in the same way as Prometheus does on scrape
+func flattenFamilies(families []*io_prometheus_client.MetricFamily, extra map[string]string) []remoteWriteSeries {
+	series := make([]remoteWriteSeries, 0)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			labels := make([]remoteWriteLabel, 0, len(metric.GetLabel())+len(extra)+2)
+			for _, label := range metric.GetLabel() {
+				labels = append(labels, remoteWriteLabel{name: label.GetName(), value: label.GetValue()})
+			}
+			for name, value := range extra {
+				labels = append(labels, remoteWriteLabel{name: name, value: value})
+			}
+			add := func(suffix string, value float64, additional ...remoteWriteLabel) {
+				current := append(append([]remoteWriteLabel{{name: "__name__", value: family.GetName() + suffix}}, labels...), additional...)
+				sort.Slice(current, func(i, j int) bool { return current[i].name < current[j].name })
+				series = append(series, remoteWriteSeries{labels: current, value: value})
+			}
+			switch {
+			case metric.GetCounter() != nil:
+				add("", metric.GetCounter().GetValue())
+			case metric.GetGauge() != nil:
+				add("", metric.GetGauge().GetValue())
+			case metric.GetHistogram() != nil:
+				histogram := metric.GetHistogram()
+				for _, bucket := range histogram.GetBucket() {
+					add("_bucket", float64(bucket.GetCumulativeCount()), remoteWriteLabel{name: "le", value: strconv.FormatFloat(bucket.GetUpperBound(), 'g', -1, 64)})
+				}
+				add("_bucket", float64(histogram.GetSampleCount()), remoteWriteLabel{name: "le", value: "+Inf"})
+				add("_sum", histogram.GetSampleSum())
+				add("_count", float64(histogram.GetSampleCount()))
+			case metric.GetSummary() != nil:
+				summary := metric.GetSummary()
+				for _, quantile := range summary.GetQuantile() {
+					add("", quantile.GetValue(), remoteWriteLabel{name: "quantile", value: strconv.FormatFloat(quantile.GetQuantile(), 'g', -1, 64)})
+				}
+				add("_sum", summary.GetSampleSum())
+				add("_count", float64(summary.GetSampleCount()))
+			case metric.GetUntyped() != nil:
+				add("", metric.GetUntyped().GetValue())
+			}
+		}
+	}
+	return series
+}
+
+// encodeWriteRequest serializes prometheus.WriteRequest protobuf message:
+// WriteRequest{timeseries=1}, TimeSeries{labels=1, samples=2}, Label{name=1, value=2}, Sample{value=1, timestamp=2}
+func encodeWriteRequest(series []remoteWriteSeries, now time.Time) []byte {
+	request := make([]byte, 0)
+	for _, s := range series {
+		timeseries := make([]byte, 0)
+		for _, label := range s.labels {
+			encoded := protowire.AppendTag(nil, 1, protowire.BytesType)
+			encoded = protowire.AppendString(encoded, label.name)
+			encoded = protowire.AppendTag(encoded, 2, protowire.BytesType)
+			encoded = protowire.AppendString(encoded, label.value)
+			timeseries = protowire.AppendTag(timeseries, 1, protowire.BytesType)
+			timeseries = protowire.AppendBytes(timeseries, encoded)
+		}
+		sample := protowire.AppendTag(nil, 1, protowire.Fixed64Type)
+		sample = protowire.AppendFixed64(sample, math.Float64bits(s.value))
+		sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+		sample = protowire.AppendVarint(sample, uint64(now.UnixMilli()))
+		timeseries = protowire.AppendTag(timeseries, 2, protowire.BytesType)
+		timeseries = protowire.AppendBytes(timeseries, sample)
+
+		request = protowire.AppendTag(request, 1, protowire.BytesType)
+		request = protowire.AppendBytes(request, timeseries)
+	}
+	return request
+}
+
+// snappyEncode produces valid snappy block which consists only of literal elements
+// Remote-write payloads are small, so we trade compression ratio for the absence of extra dependency
+func snappyEncode(data []byte) []byte {
+	encoded := binary.AppendUvarint(make([]byte, 0, len(data)+len(data)/65536*3+16), uint64(len(data)))
+	for len(data) > 0 {
+		chunk := data
+		if len(chunk) > 65536 {
+			chunk = chunk[:65536]
+		}
+		n := len(chunk) - 1
+		if n < 60 {
+			encoded = append(encoded, byte(n<<2))
+		} else if n < 1<<8 {
+			encoded = append(encoded, 60<<2, byte(n))
+		} else {
+			encoded = append(encoded, 61<<2, byte(n), byte(n>>8))
+		}
+		encoded = append(encoded, chunk...)
+		data = data[len(chunk):]
+	}
+	return encoded
+}
+
+// RunExporters exports metrics every interval and once more when returned function is called
+func RunExporters(interval time.Duration, exporters []Exporter, logger *zap.SugaredLogger) func() {
+	export := func() {
+		for _, exporter := range exporters {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			if err := exporter.Export(ctx); err != nil {
+				logger.Errorf("unable to export metrics to %v: %v", exporter.Name(), err)
+			}
+			cancel()
+		}
+	}
+	finish, finished := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				export()
+			case <-finish:
+				export()
+				return
+			}
+		}
+	}()
+	return func() {
+		close(finish)
+		<-finished
+	}
+}
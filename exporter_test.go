@@ -0,0 +1,89 @@
+package gostress
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// snappyDecodeLiterals decodes snappy block which consists only of literal elements
+func snappyDecodeLiterals(t *testing.T, data []byte) []byte {
+	length, n := binary.Uvarint(data)
+	data = data[n:]
+	decoded := make([]byte, 0, length)
+	for len(data) > 0 {
+		tag := data[0] >> 2
+		require.Equal(t, byte(0), data[0]&3)
+		size, header := int(tag)+1, 1
+		if tag == 60 {
+			size, header = int(data[1])+1, 2
+		} else if tag == 61 {
+			size, header = int(data[1])|int(data[2])<<8+1, 3
+		}
+		decoded = append(decoded, data[header:header+size]...)
+		data = data[header+size:]
+	}
+	require.Equal(t, int(length), len(decoded))
+	return decoded
+}
+
+func TestRemoteWriteExporter(t *testing.T) {
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "snappy", r.Header.Get("Content-Encoding"))
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		compressed, err := io.ReadAll(r.Body)
+		require.Nil(t, err)
+		body = snappyDecodeLiterals(t, compressed)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	metrics := NewMetrics(t.Name(), nil)
+	metrics.SentRequestCounter.Add(42)
+	metrics.ObserveLatency(Stage{}, "success", time.Millisecond)
+	exporter := NewRemoteWriteExporter(server.URL, metrics.Registry, map[string]string{"instance": "test"})
+	require.Nil(t, exporter.Export(context.Background()))
+	assert.True(t, bytes.Contains(body, []byte("gostress_sent_request_counter")))
+	assert.True(t, bytes.Contains(body, []byte("gostress_request_latency_bucket")))
+	assert.True(t, bytes.Contains(body, []byte("instance")))
+}
+
+func TestPushgatewayExporter(t *testing.T) {
+	var path string
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	metrics := NewMetrics(t.Name(), nil)
+	exporter := NewPushgatewayExporter(server.URL, "gostress", metrics.Registry, map[string]string{"instance": "test"})
+	require.Nil(t, exporter.Export(context.Background()))
+	assert.Equal(t, "/metrics/job/gostress/instance/test", path)
+	assert.True(t, bytes.Contains(body, []byte("gostress_sent_request_counter")))
+}
+
+func TestPushgatewayExporterTimeout(t *testing.T) {
+	hang := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { <-hang }))
+	defer server.Close()
+	defer close(hang)
+
+	metrics := NewMetrics(t.Name(), nil)
+	exporter := NewPushgatewayExporter(server.URL, "gostress", metrics.Registry, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	startTime := time.Now()
+	require.NotNil(t, exporter.Export(ctx))
+	assert.Less(t, time.Since(startTime), time.Second)
+}
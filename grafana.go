@@ -0,0 +1,284 @@
+package gostress
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	GoStressGrafanaTokenEnv = "GOSTRESS_GRAFANA_TOKEN"
+	// GrafanaAnnotationTag is attached to every annotation and used by the annotation query of the generated dashboard
+	GrafanaAnnotationTag = "gostress"
+)
+
+// DefaultGrafanaAnnotationKinds are events which are posted as annotations when GrafanaOptions.Kinds is empty
+var DefaultGrafanaAnnotationKinds = []string{EventRunStart, EventRunEnd, EventStageStart, EventAbort}
+
+type (
+	GrafanaOptions struct {
+		// Url is a base url of the Grafana (for example http://grafana:3000), annotations are disabled when empty
+		Url string
+		// Token is a service account token (default is the GOSTRESS_GRAFANA_TOKEN env)
+		Token string `report:"secret"`
+		// DashboardUid binds annotations to the dashboard, otherwise they are organization-wide
+		DashboardUid string
+		// Tags are attached to every annotation in addition to gostress, name, category and kind of the event
+		Tags []string
+		// Kinds of the events posted as annotations (default is DefaultGrafanaAnnotationKinds)
+		Kinds []string
+	}
+
+	// GrafanaAnnotator posts run events as annotations to the Grafana HTTP API
+	// Events are posted in background, so slow Grafana doesn't affect pacing of the load
+	GrafanaAnnotator struct {
+		Url          string
+		Token        string
+		DashboardUid string
+		Tags         []string
+		Client       *http.Client
+		kinds        map[string]bool
+		lock         sync.Mutex
+		pending      []grafanaAnnotation
+		notify       chan struct{}
+	}
+
+	grafanaAnnotation struct {
+		Time         int64    `json:"time"`
+		Tags         []string `json:"tags"`
+		Text         string   `json:"text"`
+		DashboardUid string   `json:"dashboardUID,omitempty"`
+	}
+)
+
+// NewGrafanaAnnotator creates annotator for the stress with the given name (name and category are added to the tags)
+func NewGrafanaAnnotator(name string, options GrafanaOptions) *GrafanaAnnotator {
+	token := options.Token
+	if token == "" {
+		token = os.Getenv(GoStressGrafanaTokenEnv)
+	}
+	kinds := options.Kinds
+	if len(kinds) == 0 {
+		kinds = DefaultGrafanaAnnotationKinds
+	}
+	annotator := &GrafanaAnnotator{
+		Url:          strings.TrimSuffix(options.Url, "/"),
+		Token:        token,
+		DashboardUid: options.DashboardUid,
+		Tags:         append([]string{GrafanaAnnotationTag, name, strings.SplitN(name, "/", 2)[0]}, options.Tags...),
+		Client:       &http.Client{Timeout: 10 * time.Second},
+		kinds:        make(map[string]bool, len(kinds)),
+		notify:       make(chan struct{}, 1),
+	}
+	for _, kind := range kinds {
+		annotator.kinds[kind] = true
+	}
+	return annotator
+}
+
+// Handle enqueues annotation for the event; it is intended to be subscribed to the EventLog
+func (a *GrafanaAnnotator) Handle(event Event) {
+	if !a.kinds[event.Kind] {
+		return
+	}
+	a.lock.Lock()
+	a.pending = append(a.pending, grafanaAnnotation{
+		Time:         event.Time.UnixMilli(),
+		Tags:         append(append([]string{}, a.Tags...), event.Kind),
+		Text:         event.Message,
+		DashboardUid: a.DashboardUid,
+	})
+	a.lock.Unlock()
+	select {
+	case a.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Flush posts all enqueued annotations, the ones which failed to post are kept for the next flush
+func (a *GrafanaAnnotator) Flush(ctx context.Context) error {
+	a.lock.Lock()
+	pending := a.pending
+	a.pending = nil
+	a.lock.Unlock()
+	failed, errs := make([]grafanaAnnotation, 0), make([]error, 0)
+	for _, annotation := range pending {
+		if err := a.post(ctx, annotation); err != nil {
+			failed, errs = append(failed, annotation), append(errs, err)
+		}
+	}
+	if len(failed) > 0 {
+		a.lock.Lock()
+		a.pending = append(failed, a.pending...)
+		a.lock.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+func (a *GrafanaAnnotator) post(ctx context.Context, annotation grafanaAnnotation) error {
+	body, err := json.Marshal(annotation)
+	if err != nil {
+		return fmt.Errorf("unable to serialize grafana annotation: %w", err)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, a.Url+"/api/annotations", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("unable to create grafana request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if a.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+a.Token)
+	}
+	response, err := a.Client.Do(request)
+	if err != nil {
+		return fmt.Errorf("grafana annotation request failed: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("grafana annotation request failed with status %v: %s", response.StatusCode, message)
+	}
+	return nil
+}
+
+// Start posts enqueued annotations as soon as they appear until returned function is called
+func (a *GrafanaAnnotator) Start(logger *zap.SugaredLogger) func() {
+	finish, finished := make(chan struct{}), make(chan struct{})
+	flush := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := a.Flush(ctx); err != nil {
+			logger.Errorf("unable to post grafana annotations: %v", err)
+		}
+	}
+	go func() {
+		defer close(finished)
+		for {
+			select {
+			case <-a.notify:
+				flush()
+			case <-finish:
+				flush()
+				return
+			}
+		}
+	}()
+	return func() {
+		close(finish)
+		<-finished
+	}
+}
+
+type GrafanaDashboardOptions struct {
+	// Title of the dashboard (default "gostress")
+	Title string
+	// Uid of the dashboard (default "gostress"), set GrafanaOptions.DashboardUid to the same value to bind annotations
+	Uid string
+}
+
+// GrafanaDashboard generates ready-to-import dashboard for the gostress_* metrics
+// Stresses are selected with the gostress_category and gostress_name variables, annotations are queried by the gostress tag
+func GrafanaDashboard(options GrafanaDashboardOptions) ([]byte, error) {
+	if options.Title == "" {
+		options.Title = "gostress"
+	}
+	if options.Uid == "" {
+		options.Uid = "gostress"
+	}
+	datasource := map[string]string{"type": "prometheus", "uid": "${datasource}"}
+	selector := `gostress_category=~"$category", gostress_name=~"$name"`
+	type target struct{ expr, legend string }
+	panels := make([]map[string]any, 0)
+	panel := func(title, unit string, targets ...target) {
+		index := len(panels)
+		queries := make([]map[string]any, 0, len(targets))
+		for i, target := range targets {
+			queries = append(queries, map[string]any{
+				"datasource":   datasource,
+				"expr":         target.expr,
+				"legendFormat": target.legend,
+				"refId":        string(rune('A' + i)),
+			})
+		}
+		panels = append(panels, map[string]any{
+			"id":          index + 1,
+			"type":        "timeseries",
+			"title":       title,
+			"datasource":  datasource,
+			"gridPos":     map[string]int{"x": index % 2 * 12, "y": index / 2 * 8, "w": 12, "h": 8},
+			"fieldConfig": map[string]any{"defaults": map[string]any{"unit": unit}, "overrides": []any{}},
+			"targets":     queries,
+		})
+	}
+	quantile := func(q float64, metric, extra, by string) target {
+		return target{
+			expr:   fmt.Sprintf(`histogram_quantile(%v, sum by (le, %v) (rate(%v_bucket{%v%v}[$__rate_interval])))`, q, by, metric, selector, extra),
+			legend: fmt.Sprintf("{{%v}} p%v", strings.ReplaceAll(by, ", ", "}} {{"), math.Round(q*100)),
+		}
+	}
+	panel("Throughput", "reqps",
+		target{fmt.Sprintf(`gostress_expected_rps{%v}`, selector), "{{gostress_name}} expected"},
+		target{fmt.Sprintf(`gostress_achieved_rps{%v}`, selector), "{{gostress_name}} achieved"},
+		target{fmt.Sprintf(`gostress_completed_rps{%v}`, selector), "{{gostress_name}} completed"},
+	)
+	panel("Latency of successful requests", "s",
+		quantile(0.5, "gostress_request_latency", `, status="success"`, "gostress_name"),
+		quantile(0.9, "gostress_request_latency", `, status="success"`, "gostress_name"),
+		quantile(0.99, "gostress_request_latency", `, status="success"`, "gostress_name"),
+	)
+	panel("Errors and skipped requests", "percentunit",
+		target{fmt.Sprintf(`rate(gostress_errors_request_counter{%[1]v}[$__rate_interval]) / rate(gostress_sent_request_counter{%[1]v}[$__rate_interval])`, selector), "{{gostress_name}} errors"},
+		target{fmt.Sprintf(`rate(gostress_skipped_request_counter{%[1]v}[$__rate_interval]) / rate(gostress_sent_request_counter{%[1]v}[$__rate_interval])`, selector), "{{gostress_name}} skipped"},
+	)
+	panel("Workers", "short",
+		target{fmt.Sprintf(`gostress_expected_workers{%v}`, selector), "{{gostress_name}} expected"},
+		target{fmt.Sprintf(`gostress_current_workers{%v}`, selector), "{{gostress_name}} current"},
+	)
+	panel("Phase latency p99", "s", quantile(0.99, "gostress_phase_latency", "", "gostress_name, phase"))
+	panel("Scheduling lag p99", "s", quantile(0.99, "gostress_scheduling_lag", "", "gostress_name"))
+
+	variable := func(name, query string) map[string]any {
+		return map[string]any{
+			"name":       name,
+			"type":       "query",
+			"datasource": datasource,
+			"query":      map[string]any{"query": query, "refId": name},
+			"definition": query,
+			"refresh":    2,
+			"multi":      true,
+			"includeAll": true,
+			"current":    map[string]any{"text": "All", "value": "$__all"},
+		}
+	}
+	dashboard := map[string]any{
+		"uid":           options.Uid,
+		"title":         options.Title,
+		"tags":          []string{GrafanaAnnotationTag},
+		"schemaVersion": 38,
+		"time":          map[string]string{"from": "now-1h", "to": "now"},
+		"refresh":       "10s",
+		"panels":        panels,
+		"templating": map[string]any{"list": []any{
+			map[string]any{"name": "datasource", "type": "datasource", "query": "prometheus"},
+			variable("category", "label_values(gostress_expected_rps, gostress_category)"),
+			variable("name", `label_values(gostress_expected_rps{gostress_category=~"$category"}, gostress_name)`),
+		}},
+		"annotations": map[string]any{"list": []any{map[string]any{
+			"name":       "gostress events",
+			"datasource": map[string]string{"type": "grafana", "uid": "-- Grafana --"},
+			"enable":     true,
+			"iconColor":  "orange",
+			"target":     map[string]any{"type": "tags", "tags": []string{GrafanaAnnotationTag}, "matchAny": false, "limit": 100},
+		}}},
+	}
+	return json.MarshalIndent(dashboard, "", "  ")
+}
@@ -0,0 +1,121 @@
+package gostress
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGrafanaAnnotations(t *testing.T) {
+	lock := sync.Mutex{}
+	annotations := make([]grafanaAnnotation, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/annotations", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		var annotation grafanaAnnotation
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&annotation))
+		lock.Lock()
+		annotations = append(annotations, annotation)
+		lock.Unlock()
+	}))
+	defer server.Close()
+
+	logger := zaptest.NewLogger(t).Sugar()
+	options := GoStressOptions{
+		WorkerTimeout: time.Second,
+		Schedule: LoadSchedule{
+			{Name: "warmup", Rps: 10, Workers: 1, Duration: 300 * time.Millisecond},
+			{Name: "peak", Rps: 10, Workers: 2, Duration: 300 * time.Millisecond},
+		},
+		Grafana: GrafanaOptions{Url: server.URL + "/", Token: "secret", DashboardUid: "gostress", Tags: []string{"ci"}},
+	}
+	stress, shutdown := NewStress("grafana/annotations", options, logger, &cliFailures{logger: logger}, func(ctx RequestContext) error { return nil })
+	defer shutdown()
+	stress.RunLocal(context.Background())
+
+	lock.Lock()
+	defer lock.Unlock()
+	kinds := make([]string, 0, len(annotations))
+	for _, annotation := range annotations {
+		kinds = append(kinds, annotation.Tags[len(annotation.Tags)-1])
+		assert.Equal(t, []string{"gostress", "grafana/annotations", "grafana", "ci"}, annotation.Tags[:4])
+		assert.Equal(t, "gostress", annotation.DashboardUid)
+		assert.NotZero(t, annotation.Time)
+	}
+	assert.Equal(t, []string{EventRunStart, EventStageStart, EventStageStart, EventRunEnd}, kinds)
+}
+
+func TestGrafanaAnnotationsFailure(t *testing.T) {
+	lock, failures, posted := sync.Mutex{}, 1, make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var annotation grafanaAnnotation
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&annotation))
+		lock.Lock()
+		defer lock.Unlock()
+		if failures > 0 {
+			failures--
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		posted = append(posted, annotation.Text)
+	}))
+	defer server.Close()
+	annotator := NewGrafanaAnnotator("grafana", GrafanaOptions{Url: server.URL})
+	annotator.Handle(Event{Time: time.Now(), Kind: EventAbort, Message: "aborted"})
+	annotator.Handle(Event{Time: time.Now(), Kind: EventPoolResize, Message: "ignored"})
+	annotator.Handle(Event{Time: time.Now(), Kind: EventRunEnd, Message: "finished"})
+	err := annotator.Flush(context.Background())
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "status 401")
+	// failure of the first annotation doesn't prevent posting of the next ones, failed one is retried
+	assert.Equal(t, []string{"finished"}, posted)
+	assert.Nil(t, annotator.Flush(context.Background()))
+	assert.Equal(t, []string{"finished", "aborted"}, posted)
+	assert.Nil(t, annotator.Flush(context.Background()))
+	assert.Len(t, posted, 2)
+}
+
+func TestGrafanaDashboard(t *testing.T) {
+	content, err := GrafanaDashboard(GrafanaDashboardOptions{Title: "stress", Uid: "stress-uid"})
+	require.Nil(t, err)
+	var dashboard struct {
+		Uid    string `json:"uid"`
+		Title  string `json:"title"`
+		Panels []struct {
+			Title   string `json:"title"`
+			Targets []struct {
+				Expr         string `json:"expr"`
+				LegendFormat string `json:"legendFormat"`
+			} `json:"targets"`
+		} `json:"panels"`
+		Templating struct {
+			List []struct {
+				Name string `json:"name"`
+			} `json:"list"`
+		} `json:"templating"`
+	}
+	require.Nil(t, json.Unmarshal(content, &dashboard))
+	assert.Equal(t, "stress-uid", dashboard.Uid)
+	assert.Equal(t, "stress", dashboard.Title)
+	require.Len(t, dashboard.Templating.List, 3)
+	assert.Equal(t, "name", dashboard.Templating.List[2].Name)
+	require.NotEmpty(t, dashboard.Panels)
+	for _, panel := range dashboard.Panels {
+		for _, target := range panel.Targets {
+			assert.Contains(t, target.Expr, `gostress_category=~"$category", gostress_name=~"$name"`)
+		}
+	}
+	assert.Equal(t, "{{gostress_name}} p99", dashboard.Panels[1].Targets[2].LegendFormat)
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	assert.Equal(t, cliExitOk, RunCli([]string{"grafana-dashboard", "-uid", "cli"}, stdout, stderr))
+	assert.Contains(t, stdout.String(), `"uid": "cli"`)
+}
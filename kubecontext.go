@@ -30,6 +30,8 @@ type KubeContext struct {
 	logger *zap.SugaredLogger
 	config *rest.Config
 	client *kubernetes.Clientset
+	// Events receives pod lifecycle events
+	Events *EventLog
 }
 
 type KubePod struct {
@@ -55,6 +57,13 @@ func NewKubeContext(logger *zap.SugaredLogger) (*KubeContext, error) {
 	return &KubeContext{logger: logger, client: client, config: config}, nil
 }
 
+const (
+	// goStressPidFile contains pid of the stress process executed in the pod
+	goStressPidFile = "/work/gostress.pid"
+	// podEntrypoint keeps the pod alive and forwards SIGTERM to the stress process, waiting for its drain before exit
+	podEntrypoint = `trap 'for pid in $(cat %[1]v 2>/dev/null); do kill -TERM $pid 2>/dev/null; while kill -0 $pid 2>/dev/null; do sleep 1; done; done; exit 0' TERM; sleep %[2]v & wait`
+)
+
 type podOpts struct {
 	name        string
 	metricsPort int
@@ -63,6 +72,7 @@ type podOpts struct {
 	memory      string
 	labels      map[string]string
 	timeout     time.Duration
+	gracePeriod time.Duration
 }
 
 type PodOpts interface{ apply(opts *podOpts) }
@@ -75,6 +85,7 @@ type (
 	podLabels      map[string]string
 	podAutoLabels  struct{}
 	podTimeout     time.Duration
+	podGracePeriod time.Duration
 )
 
 func (p podMetricsPort) apply(opts *podOpts) { opts.metricsPort = int(p) }
@@ -82,6 +93,7 @@ func (p podGoVersion) apply(opts *podOpts)   { opts.goVersion = string(p) }
 func (p podCpu) apply(opts *podOpts)         { opts.cpu = string(p) }
 func (p podMemory) apply(opts *podOpts)      { opts.memory = string(p) }
 func (p podTimeout) apply(opts *podOpts)     { opts.timeout = time.Duration(p) }
+func (p podGracePeriod) apply(opts *podOpts) { opts.gracePeriod = time.Duration(p) }
 func (p podAutoLabels) apply(opts *podOpts)  { opts.labels["id"] = opts.name }
 func (p podLabels) apply(opts *podOpts) {
 	for k, v := range p {
@@ -89,11 +101,15 @@ func (p podLabels) apply(opts *podOpts) {
 	}
 }
 
-func WithMetricsPort(port int) PodOpts               { return podMetricsPort(port) }
-func WithGoVersion(goVersion string) PodOpts         { return podGoVersion(goVersion) }
-func WithCpu(cpu string) PodOpts                     { return podCpu(cpu) }
-func WithMemory(memory string) PodOpts               { return podMemory(memory) }
-func WithTimeout(timeout time.Duration) PodOpts      { return podTimeout(timeout) }
+func WithMetricsPort(port int) PodOpts          { return podMetricsPort(port) }
+func WithGoVersion(goVersion string) PodOpts    { return podGoVersion(goVersion) }
+func WithCpu(cpu string) PodOpts                { return podCpu(cpu) }
+func WithMemory(memory string) PodOpts          { return podMemory(memory) }
+func WithTimeout(timeout time.Duration) PodOpts { return podTimeout(timeout) }
+
+// WithTerminationGracePeriod sets time between SIGTERM and SIGKILL of the pod (RunK8s sets it to the drain timeout of the stress)
+func WithTerminationGracePeriod(period time.Duration) PodOpts { return podGracePeriod(period) }
+
 func WithPodAutoLabels() PodOpts                     { return podAutoLabels{} }
 func WithPodLabels(labels map[string]string) PodOpts { return podLabels(labels) }
 
@@ -105,6 +121,7 @@ func (kube *KubeContext) GetPodObject(namespace, name string, modifiers ...PodOp
 		cpu:         "1",
 		memory:      "1Gi",
 		timeout:     5 * time.Hour,
+		gracePeriod: 30 * time.Second,
 		labels:      make(map[string]string, 0),
 	}
 	for _, modifier := range modifiers {
@@ -113,6 +130,7 @@ func (kube *KubeContext) GetPodObject(namespace, name string, modifiers ...PodOp
 	for k, v := range options.labels {
 		options.labels[k] = K8sNormalize(v)
 	}
+	gracePeriod := int64(options.gracePeriod.Seconds())
 	return &v1.Pod{
 		ObjectMeta: v1meta.ObjectMeta{
 			Namespace: namespace,
@@ -138,14 +156,16 @@ func (kube *KubeContext) GetPodObject(namespace, name string, modifiers ...PodOp
 							v1.ResourceMemory: resource.MustParse(options.memory),
 						},
 					},
-					Command: []string{"sleep", strconv.Itoa(int(options.timeout.Seconds()))},
+					Command: []string{"bash", "-c", fmt.Sprintf(podEntrypoint, goStressPidFile, int(options.timeout.Seconds()))},
 					Env: []v1.EnvVar{
 						{Name: GoStressEnv, Value: GoStressEnvK8s},
+						{Name: GoStressMetricsPortEnv, Value: strconv.Itoa(options.metricsPort)},
 					},
 				},
 			},
-			DNSPolicy:     v1.DNSClusterFirst,
-			RestartPolicy: v1.RestartPolicyNever,
+			DNSPolicy:                     v1.DNSClusterFirst,
+			RestartPolicy:                 v1.RestartPolicyNever,
+			TerminationGracePeriodSeconds: &gracePeriod,
 		},
 	}
 }
@@ -158,6 +178,7 @@ func (kube *KubeContext) CreateOneTimePod(ctx context.Context, podObject *v1.Pod
 		return nil, fmt.Errorf("failed to create k8s pod %v: %w", podObject.Name, err)
 	} else {
 		kube.logger.Errorf("succeed to create k8s pod %v", pod.Name)
+		kube.Events.Emit(EventPod, fmt.Sprintf("pod %v created", pod.Name), "pod", pod.Name, "status", "created")
 	}
 	return &KubePod{kube: kube, object: pod}, nil
 }
@@ -176,6 +197,7 @@ func (pod *KubePod) Wait(timeout, interval time.Duration) error {
 			pod.kube.logger.Errorf("failed to get information for pod %v: %v", pod.object.Name, err)
 		}
 		if current.Status.Phase == v1.PodRunning {
+			pod.kube.Events.Emit(EventPod, fmt.Sprintf("pod %v is running", pod.object.Name), "pod", pod.object.Name, "status", "running")
 			break
 		}
 		pod.kube.logger.Debugf("found pod %v in phase %v", pod.object.Name, current.Status.Phase)
@@ -194,6 +216,7 @@ func (pod *KubePod) Shutdown(timeout time.Duration) {
 		pod.kube.logger.Errorf("failed to delete k8s pod %v: %v", pod.object.Name, err)
 	} else {
 		pod.kube.logger.Infof("succeed to delete k8s pod %v", pod.object.Name)
+		pod.kube.Events.Emit(EventPod, fmt.Sprintf("pod %v deleted", pod.object.Name), "pod", pod.object.Name, "status", "deleted")
 	}
 }
 
@@ -214,13 +237,8 @@ func init() {
 	scheme.AddKnownTypes(schema.GroupVersion{Group: "meta.k8s.io", Version: "v1"}, &v1.PodExecOptions{})
 }
 
-func (workspace *KubePodWorkspace) Exec(ctx context.Context, c string, detach bool) ([]string, error) {
-	background := ""
-	if detach {
-		background = "&"
-	}
-	sprintf := fmt.Sprintf("mkdir -p %v && cd %v && %v %v", workspace.Directory(), workspace.Directory(), c, background)
-	command := []string{"bash", "-c", sprintf}
+// stream executes command in the container of the pod with the given streams attached (stdin is attached only when not nil)
+func (workspace *KubePodWorkspace) stream(ctx context.Context, command []string, stdin io.Reader, stdout, stderr io.Writer) error {
 	req := workspace.pod.kube.client.
 		RESTClient().
 		Post().
@@ -232,38 +250,60 @@ func (workspace *KubePodWorkspace) Exec(ctx context.Context, c string, detach bo
 		VersionedParams(&v1.PodExecOptions{
 			Container: workspace.pod.object.Spec.Containers[0].Name,
 			Command:   command,
-			Stdin:     false,
+			Stdin:     stdin != nil,
 			Stdout:    true,
 			Stderr:    true,
 		}, codec)
 	workspace.pod.kube.logger.Infof("ready to execute command: %#v", command)
 	exec, err := remotecommand.NewSPDYExecutor(workspace.pod.kube.config, "POST", req.URL())
 	if err != nil {
-		return nil, fmt.Errorf("unable to create SPDY executor: %w", err)
+		return fmt.Errorf("unable to create SPDY executor: %w", err)
 	}
-	output := make([]string, 0)
-	stdoutReader, stdoutWriter := io.Pipe()
-	stderrReader, stderrWriter := io.Pipe()
-	go func() {
-		reader := bufio.NewScanner(stdoutReader)
-		for reader.Scan() {
-			output = append(output, reader.Text())
-			workspace.pod.kube.logger.Infof("[stdout]: %v", reader.Text())
-		}
-	}()
+	return exec.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdin:             stdin,
+		Stdout:            stdout,
+		Stderr:            stderr,
+		Tty:               false,
+		TerminalSizeQueue: nil,
+	})
+}
+
+// logLines returns writer which passes every written line to the handler
+// Returned function closes the writer and waits until all written lines are handled
+func logLines(handler func(line string)) (io.Writer, func()) {
+	reader, writer := io.Pipe()
+	done := make(chan struct{})
 	go func() {
-		reader := bufio.NewScanner(stderrReader)
-		for reader.Scan() {
-			workspace.pod.kube.logger.Errorf("[stderr]: %v", reader.Text())
+		defer close(done)
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			handler(scanner.Text())
 		}
+		// scanner stops on too long line, the rest is discarded so the writer isn't blocked
+		_, _ = io.Copy(io.Discard, reader)
 	}()
-	err = exec.StreamWithContext(ctx, remotecommand.StreamOptions{
-		Stdin:             nil,
-		Stdout:            stdoutWriter,
-		Stderr:            stderrWriter,
-		Tty:               false,
-		TerminalSizeQueue: nil,
+	return writer, func() {
+		_ = writer.Close()
+		<-done
+	}
+}
+
+func (workspace *KubePodWorkspace) Exec(ctx context.Context, c string, detach bool) ([]string, error) {
+	background := ""
+	if detach {
+		background = "&"
+	}
+	sprintf := fmt.Sprintf("mkdir -p %v && cd %v && %v %v", workspace.Directory(), workspace.Directory(), c, background)
+	command := []string{"bash", "-c", sprintf}
+	output := make([]string, 0)
+	stdout, closeStdout := logLines(func(line string) {
+		output = append(output, line)
+		workspace.pod.kube.logger.Infof("[stdout]: %v", line)
 	})
+	stderr, closeStderr := logLines(func(line string) { workspace.pod.kube.logger.Errorf("[stderr]: %v", line) })
+	err := workspace.stream(ctx, command, nil, stdout, stderr)
+	closeStdout()
+	closeStderr()
 	if err != nil {
 		return nil, fmt.Errorf("unable to execute command %#v: %w", command, err)
 	}
@@ -273,48 +313,11 @@ func (workspace *KubePodWorkspace) Exec(ctx context.Context, c string, detach bo
 
 func (workspace *KubePodWorkspace) execStdin(ctx context.Context, content []byte, c string) error {
 	command := []string{"bash", "-c", fmt.Sprintf("mkdir -p %v && cd %v && %v", workspace.Directory(), workspace.Directory(), c)}
-	req := workspace.pod.kube.client.
-		RESTClient().
-		Post().
-		Prefix("api", "v1").
-		Resource("pods").
-		Namespace(workspace.pod.object.Namespace).
-		Name(workspace.pod.object.Name).
-		SubResource("exec").
-		VersionedParams(&v1.PodExecOptions{
-			Container: workspace.pod.object.Spec.Containers[0].Name,
-			Command:   command,
-			Stdin:     true,
-			Stdout:    true,
-			Stderr:    true,
-		}, codec)
-	workspace.pod.kube.logger.Infof("ready to execute command: %#v", command)
-
-	exec, err := remotecommand.NewSPDYExecutor(workspace.pod.kube.config, "POST", req.URL())
-	if err != nil {
-		return fmt.Errorf("unable to create SPDY executor: %w", err)
-	}
-	stdoutReader, stdoutWriter := io.Pipe()
-	stderrReader, stderrWriter := io.Pipe()
-	go func() {
-		reader := bufio.NewScanner(stdoutReader)
-		for reader.Scan() {
-			workspace.pod.kube.logger.Infof("[stdout]: %v", reader.Text())
-		}
-	}()
-	go func() {
-		reader := bufio.NewScanner(stderrReader)
-		for reader.Scan() {
-			workspace.pod.kube.logger.Errorf("[stderr]: %v", reader.Text())
-		}
-	}()
-	err = exec.StreamWithContext(ctx, remotecommand.StreamOptions{
-		Stdin:             bytes.NewReader(content),
-		Stdout:            stdoutWriter,
-		Stderr:            stderrWriter,
-		Tty:               false,
-		TerminalSizeQueue: nil,
-	})
+	stdout, closeStdout := logLines(func(line string) { workspace.pod.kube.logger.Infof("[stdout]: %v", line) })
+	stderr, closeStderr := logLines(func(line string) { workspace.pod.kube.logger.Errorf("[stderr]: %v", line) })
+	err := workspace.stream(ctx, command, bytes.NewReader(content), stdout, stderr)
+	closeStdout()
+	closeStderr()
 	if err != nil {
 		return fmt.Errorf("unable to execute command %#v: %w", command, err)
 	}
@@ -397,3 +400,13 @@ func (workspace *KubePodWorkspace) CopyDir(ctx context.Context, directory string
 	}
 	return workspace.execStdin(ctx, buffer.Bytes(), "tar -xmf -")
 }
+
+// ReadFile returns content of the file from the pod workspace
+func (workspace *KubePodWorkspace) ReadFile(ctx context.Context, target string) ([]byte, error) {
+	command := []string{"bash", "-c", fmt.Sprintf("cd %v && cat %v", workspace.Directory(), target)}
+	stdout, stderr := bytes.NewBuffer(make([]byte, 0)), bytes.NewBuffer(make([]byte, 0))
+	if err := workspace.stream(ctx, command, nil, stdout, stderr); err != nil {
+		return nil, fmt.Errorf("unable to read file %v: %w (stderr: %v)", target, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,62 @@
+package gostress
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestPodObject(t *testing.T) {
+	pod := (&KubeContext{}).GetPodObject("default", "gostress-TestStress/read+write", WithTerminationGracePeriod(time.Minute))
+	assert.Equal(t, "gostress-teststress-read-write", pod.Name)
+	require.NotNil(t, pod.Spec.TerminationGracePeriodSeconds)
+	assert.Equal(t, int64(60), *pod.Spec.TerminationGracePeriodSeconds)
+	assert.Contains(t, strings.Join(pod.Spec.Containers[0].Command, " "), goStressPidFile)
+	// remote run serves metrics on the port advertised to prometheus
+	assert.Contains(t, pod.Spec.Containers[0].Env, v1.EnvVar{Name: GoStressMetricsPortEnv, Value: pod.Annotations["prometheus.io/port"]})
+}
+
+func TestPodEntrypointDrain(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash is not available")
+	}
+	pidFile := filepath.Join(t.TempDir(), "gostress.pid")
+	// stress process is started in the detached way and needs 1s to drain after SIGTERM
+	stress := exec.Command("bash", "-c", fmt.Sprintf(`echo $BASHPID > %v && exec bash -c 'trap "sleep 1; exit 0" TERM; sleep 30 & wait' &`, pidFile))
+	require.Nil(t, stress.Run())
+	require.Eventually(t, func() bool {
+		content, err := os.ReadFile(pidFile)
+		return err == nil && len(content) > 0
+	}, time.Second, 10*time.Millisecond)
+
+	entrypoint := exec.Command("bash", "-c", fmt.Sprintf(podEntrypoint, pidFile, 30))
+	require.Nil(t, entrypoint.Start())
+	time.Sleep(200 * time.Millisecond)
+	startTime := time.Now()
+	require.Nil(t, entrypoint.Process.Signal(syscall.SIGTERM))
+	require.Nil(t, entrypoint.Wait())
+	assert.GreaterOrEqual(t, time.Since(startTime), time.Second, "entrypoint must wait for the drain of the stress process")
+}
+
+func TestLogLines(t *testing.T) {
+	lines := make([]string, 0)
+	writer, wait := logLines(func(line string) { lines = append(lines, line) })
+	_, err := fmt.Fprint(writer, "first\nsecond\nlast")
+	require.Nil(t, err)
+	wait()
+	assert.Equal(t, []string{"first", "second", "last"}, lines)
+
+	// line longer than the scanner buffer must not block the writer
+	writer, wait = logLines(func(line string) {})
+	_, err = writer.Write([]byte(strings.Repeat("x", 128*1024) + "\n"))
+	require.Nil(t, err)
+	wait()
+}
@@ -0,0 +1,287 @@
+package gostress
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencySubBucketBits defines amount of linear sub-buckets (2^bits) inside every power-of-two range
+// of the LatencyHistogram; 128 sub-buckets keep relative error of every recorded value below 1%
+const latencySubBucketBits = 7
+
+const latencySubBuckets = 1 << latencySubBucketBits
+
+var DefaultLatencyBuckets = []float64{0.001, 0.005, 0.01, 0.02, 0.05, 0.1, 0.2, 0.3, 0.5, 0.7, 1.0, 5.0}
+
+// LatencyHistogram is a log-linear (HDR-style) histogram of durations with nanosecond resolution
+// Histograms with same layout can be merged together without loss of precision
+type LatencyHistogram struct {
+	Counts []int64
+	Count  int64
+	Sum    time.Duration
+	Min    time.Duration
+	Max    time.Duration
+}
+
+func NewLatencyHistogram() *LatencyHistogram { return &LatencyHistogram{} }
+
+func latencyIndex(value int64) int {
+	if value < latencySubBuckets {
+		return int(value)
+	}
+	shift := bits.Len64(uint64(value)) - latencySubBucketBits - 1
+	return (shift+1)*latencySubBuckets + int(value>>shift) - latencySubBuckets
+}
+
+func latencyValue(index int) int64 {
+	if index < latencySubBuckets {
+		return int64(index)
+	}
+	shift := index/latencySubBuckets - 1
+	top := int64(index%latencySubBuckets + latencySubBuckets)
+	lower, upper := top<<shift, (top+1)<<shift-1
+	return lower + (upper-lower)/2
+}
+
+func (h *LatencyHistogram) Record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	index := latencyIndex(int64(d))
+	if index >= len(h.Counts) {
+		counts := make([]int64, index+1)
+		copy(counts, h.Counts)
+		h.Counts = counts
+	}
+	h.Counts[index]++
+	if h.Count == 0 || d < h.Min {
+		h.Min = d
+	}
+	if d > h.Max {
+		h.Max = d
+	}
+	h.Count++
+	h.Sum += d
+}
+
+func (h *LatencyHistogram) Merge(other *LatencyHistogram) {
+	if other.Count == 0 {
+		return
+	}
+	if len(other.Counts) > len(h.Counts) {
+		counts := make([]int64, len(other.Counts))
+		copy(counts, h.Counts)
+		h.Counts = counts
+	}
+	for i, count := range other.Counts {
+		h.Counts[i] += count
+	}
+	if h.Count == 0 || other.Min < h.Min {
+		h.Min = other.Min
+	}
+	if other.Max > h.Max {
+		h.Max = other.Max
+	}
+	h.Count += other.Count
+	h.Sum += other.Sum
+}
+
+// Sub returns histogram of values recorded after the prev snapshot of the same histogram was taken
+// Min and Max of the result are restored from the buckets and so are precise only up to the bucket width
+func (h *LatencyHistogram) Sub(prev *LatencyHistogram) *LatencyHistogram {
+	diff := &LatencyHistogram{Counts: make([]int64, len(h.Counts)), Count: h.Count - prev.Count, Sum: h.Sum - prev.Sum}
+	for i, count := range h.Counts {
+		if i < len(prev.Counts) {
+			count -= prev.Counts[i]
+		}
+		diff.Counts[i] = count
+		if count == 0 {
+			continue
+		}
+		value := time.Duration(latencyValue(i))
+		if value < h.Min {
+			value = h.Min
+		}
+		if value > h.Max {
+			value = h.Max
+		}
+		if diff.Min == 0 || value < diff.Min {
+			diff.Min = value
+		}
+		diff.Max = value
+	}
+	return diff
+}
+
+type latencyHistogramJson struct {
+	Buckets [][2]int64    `json:"buckets"`
+	Count   int64         `json:"count"`
+	Sum     time.Duration `json:"sum"`
+	Min     time.Duration `json:"min"`
+	Max     time.Duration `json:"max"`
+}
+
+// MarshalJSON stores only non-empty buckets as (index, count) pairs in order to keep serialized histogram compact
+func (h *LatencyHistogram) MarshalJSON() ([]byte, error) {
+	sparse := latencyHistogramJson{Buckets: make([][2]int64, 0), Count: h.Count, Sum: h.Sum, Min: h.Min, Max: h.Max}
+	for i, count := range h.Counts {
+		if count != 0 {
+			sparse.Buckets = append(sparse.Buckets, [2]int64{int64(i), count})
+		}
+	}
+	return json.Marshal(sparse)
+}
+
+func (h *LatencyHistogram) UnmarshalJSON(data []byte) error {
+	var sparse latencyHistogramJson
+	if err := json.Unmarshal(data, &sparse); err != nil {
+		return err
+	}
+	*h = LatencyHistogram{Count: sparse.Count, Sum: sparse.Sum, Min: sparse.Min, Max: sparse.Max}
+	for _, bucket := range sparse.Buckets {
+		if bucket[0] < 0 || bucket[0] > int64(latencyIndex(math.MaxInt64)) {
+			return fmt.Errorf("invalid latency histogram bucket index: %v", bucket[0])
+		}
+		if int(bucket[0]) >= len(h.Counts) {
+			counts := make([]int64, bucket[0]+1)
+			copy(counts, h.Counts)
+			h.Counts = counts
+		}
+		h.Counts[bucket[0]] = bucket[1]
+	}
+	return nil
+}
+
+func (h *LatencyHistogram) Copy() *LatencyHistogram {
+	c := *h
+	c.Counts = append([]int64(nil), h.Counts...)
+	return &c
+}
+
+func (h *LatencyHistogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Quantile returns value v such that at least q fraction of recorded values are less or equal to v
+func (h *LatencyHistogram) Quantile(q float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	if q >= 1 {
+		return h.Max
+	}
+	rank := int64(math.Ceil(q * float64(h.Count)))
+	if rank < 1 {
+		rank = 1
+	}
+	cumulative := int64(0)
+	for i, count := range h.Counts {
+		cumulative += count
+		if cumulative >= rank {
+			value := time.Duration(latencyValue(i))
+			if value < h.Min {
+				return h.Min
+			}
+			if value > h.Max {
+				return h.Max
+			}
+			return value
+		}
+	}
+	return h.Max
+}
+
+// LatencyRecorder keeps LatencyHistogram for every stage and status alongside with prometheus RequestLatency histogram
+type LatencyRecorder struct {
+	lock       sync.Mutex
+	histograms map[latencyKey]*LatencyHistogram
+	stages     map[int]Stage
+}
+
+type latencyKey struct {
+	stage  int
+	status string
+}
+
+func NewLatencyRecorder() *LatencyRecorder {
+	return &LatencyRecorder{histograms: make(map[latencyKey]*LatencyHistogram), stages: make(map[int]Stage)}
+}
+
+func (r *LatencyRecorder) Record(stage Stage, status string, d time.Duration) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	key := latencyKey{stage: stage.Index, status: status}
+	histogram, ok := r.histograms[key]
+	if !ok {
+		histogram = NewLatencyHistogram()
+		r.histograms[key] = histogram
+		r.stages[stage.Index] = stage
+	}
+	histogram.Record(d)
+}
+
+// Snapshot returns copies of histograms for every status merged across all stages, so caller can use them without synchronization
+func (r *LatencyRecorder) Snapshot() map[string]*LatencyHistogram {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	snapshot := make(map[string]*LatencyHistogram)
+	for key, histogram := range r.histograms {
+		if merged, ok := snapshot[key.status]; ok {
+			merged.Merge(histogram)
+		} else {
+			snapshot[key.status] = histogram.Copy()
+		}
+	}
+	return snapshot
+}
+
+// StageSnapshot returns copies of histograms for every status of requests triggered during the given stage
+func (r *LatencyRecorder) StageSnapshot(stage int) map[string]*LatencyHistogram {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	snapshot := make(map[string]*LatencyHistogram)
+	for key, histogram := range r.histograms {
+		if key.stage == stage {
+			snapshot[key.status] = histogram.Copy()
+		}
+	}
+	return snapshot
+}
+
+// Stages returns all stages with at least one recorded request ordered by index
+func (r *LatencyRecorder) Stages() []Stage {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	stages := make([]Stage, 0, len(r.stages))
+	for _, stage := range r.stages {
+		stages = append(stages, stage)
+	}
+	sort.Slice(stages, func(i, j int) bool { return stages[i].Index < stages[j].Index })
+	return stages
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// roundLatency keeps only 3-4 significant digits of the duration in order to make it readable
+func roundLatency(d time.Duration) time.Duration {
+	unit := time.Nanosecond
+	for d/unit >= 10000 {
+		unit *= 10
+	}
+	return d.Round(unit)
+}
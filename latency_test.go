@@ -0,0 +1,47 @@
+package gostress
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestLatencyHistogramAccuracy(t *testing.T) {
+	h := NewLatencyHistogram()
+	values := make([]time.Duration, 0, 100000)
+	for i := 0; i < 100000; i++ {
+		value := time.Duration(math.Exp(rand.Float64()*20)) * time.Microsecond
+		values = append(values, value)
+		h.Record(value)
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	for _, q := range []float64{0.5, 0.9, 0.99, 0.999, 0.9999} {
+		exact := values[int(math.Ceil(q*float64(len(values))))-1]
+		actual := h.Quantile(q)
+		assert.InEpsilon(t, float64(exact), float64(actual), 0.01, "q=%v", q)
+	}
+	assert.Equal(t, values[len(values)-1], h.Quantile(1))
+	assert.Equal(t, values[0], h.Min)
+}
+
+func TestLatencyHistogramMerge(t *testing.T) {
+	a, b, all := NewLatencyHistogram(), NewLatencyHistogram(), NewLatencyHistogram()
+	for i := 1; i <= 1000; i++ {
+		value := time.Duration(i) * time.Millisecond
+		if i%2 == 0 {
+			a.Record(value)
+		} else {
+			b.Record(value)
+		}
+		all.Record(value)
+	}
+	a.Merge(b)
+	assert.Equal(t, all.Count, a.Count)
+	assert.Equal(t, all.Sum, a.Sum)
+	assert.Equal(t, all.Min, a.Min)
+	assert.Equal(t, all.Max, a.Max)
+	assert.Equal(t, all.Quantile(0.99), a.Quantile(0.99))
+}
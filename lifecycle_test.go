@@ -0,0 +1,75 @@
+package gostress
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zaptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLifecycleHooks(t *testing.T) {
+	logger := zaptest.NewLogger(t).Sugar()
+	setups, teardowns, mismatches := int32(0), int32(0), int32(0)
+	options := GoStressOptions{
+		WorkerTimeout: time.Second,
+		Schedule:      LoadSchedule{{Rps: 20, Workers: 2, Duration: time.Minute}},
+		Setup: func(ctx context.Context) (any, error) {
+			atomic.AddInt32(&setups, 1)
+			return "seeded", nil
+		},
+		Teardown: func(ctx context.Context, state any) error {
+			atomic.AddInt32(&teardowns, 1)
+			assert.Nil(t, ctx.Err())
+			return fmt.Errorf("unable to cleanup %v", state)
+		},
+	}
+	failures := &cliFailures{logger: logger}
+	stress, shutdown := NewStress(t.Name(), options, logger, failures, func(ctx RequestContext) error {
+		if ctx.State != "seeded" {
+			atomic.AddInt32(&mismatches, 1)
+		}
+		return nil
+	})
+	defer shutdown()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	result := stress.RunLocal(ctx)
+	assert.True(t, result.Aborted)
+	assert.Greater(t, result.Total.Sent, int64(0))
+	assert.Equal(t, int32(1), setups)
+	assert.Equal(t, int32(1), teardowns)
+	assert.Equal(t, int32(0), mismatches)
+	assert.Equal(t, "teardown failed: unable to cleanup seeded", result.TeardownError)
+	assert.True(t, failures.Failed())
+}
+
+func TestLifecycleSetupFailure(t *testing.T) {
+	logger := zaptest.NewLogger(t).Sugar()
+	calls, teardowns := int32(0), int32(0)
+	options := GoStressOptions{
+		WorkerTimeout: time.Second,
+		Schedule:      LoadSchedule{{Rps: 20, Workers: 2, Duration: time.Second}},
+		Setup: func(ctx context.Context) (any, error) {
+			return nil, fmt.Errorf("database is not available")
+		},
+		Teardown: func(ctx context.Context, state any) error {
+			atomic.AddInt32(&teardowns, 1)
+			return nil
+		},
+	}
+	failures := &cliFailures{logger: logger}
+	stress, shutdown := NewStress(t.Name(), options, logger, failures, func(ctx RequestContext) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	})
+	defer shutdown()
+	result := stress.RunLocal(context.Background())
+	assert.True(t, result.Aborted)
+	assert.Equal(t, "setup failed: database is not available", result.SetupError)
+	assert.Equal(t, int32(0), calls)
+	assert.Equal(t, int32(0), teardowns)
+	assert.True(t, failures.Failed())
+}
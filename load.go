@@ -11,12 +11,21 @@ type (
 	Id             int64
 	RequestContext struct {
 		Id     Id
+		Worker Id
 		Ctx    context.Context
 		Logger *zap.SugaredLogger
+		// State is an output of the GoStressOptions.Setup shared by all requests
+		State any
+		// metrics and stage are used to record phases of the request
+		metrics *Metrics
+		stage   Stage
 	}
 )
 
 func (p *LoadParams) String() string {
+	if p.Name != "" {
+		return fmt.Sprintf("{name: %v, rps: %v, workers: %v, duration: %v}", p.Name, p.Rps, p.Workers, p.Duration)
+	}
 	return fmt.Sprintf("{rps: %v, workers: %v, duration: %v}", p.Rps, p.Workers, p.Duration)
 }
 
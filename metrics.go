@@ -2,69 +2,213 @@ package gostress
 
 import (
 	"github.com/prometheus/client_golang/prometheus"
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
-var (
-	ExpectedRpsGauge      = prometheus.NewGauge(prometheus.GaugeOpts{})
-	ExpectedWorkersGauge  = prometheus.NewGauge(prometheus.GaugeOpts{})
-	CurrentWorkersGauge   = prometheus.NewGauge(prometheus.GaugeOpts{})
-	SentRequestCounter    = prometheus.NewCounter(prometheus.CounterOpts{})
-	SkippedRequestCounter = prometheus.NewCounter(prometheus.CounterOpts{})
-	ErrorsCounter         = prometheus.NewCounter(prometheus.CounterOpts{})
-	RequestLatency        = prometheus.NewHistogramVec(prometheus.HistogramOpts{}, []string{"status"})
-)
+type Metrics struct {
+	Registry              *prometheus.Registry
+	ExpectedRpsGauge      prometheus.Gauge
+	ExpectedWorkersGauge  prometheus.Gauge
+	CurrentWorkersGauge   prometheus.Gauge
+	SentRequestCounter    prometheus.Counter
+	SkippedRequestCounter prometheus.Counter
+	ErrorsCounter         prometheus.Counter
+	RequestLatency        *prometheus.HistogramVec
+	Latency               *LatencyRecorder
+	PhaseLatency          *prometheus.HistogramVec
+	Phases                *LatencyRecorder
+	ErrorClasses          *ErrorRecorder
+	AchievedRpsGauge      prometheus.GaugeFunc
+	CompletedRpsGauge     prometheus.GaugeFunc
+	SchedulingLag         prometheus.Histogram
+	Saturation            *SaturationTracker
+	Events                *EventLog
+	stage                 atomic.Value
+	sentRate              *slidingRate
+	completedRate         *slidingRate
+}
+
+// rateWindow is a size of the sliding window (in seconds) for achieved and completed rps
+const rateWindow = 5
+
+func NewMetrics(name string, latencyBuckets []float64) *Metrics {
+	return NewLabeledMetrics(name, latencyBuckets, nil)
+}
 
-func registerMetrics(name string) {
+// NewLabeledMetrics creates metrics with extra constant labels attached to every metric
+func NewLabeledMetrics(name string, latencyBuckets []float64, extra prometheus.Labels) *Metrics {
+	if len(latencyBuckets) == 0 {
+		latencyBuckets = DefaultLatencyBuckets
+	}
 	tokens := strings.SplitN(name, "/", 2)
 	labels := prometheus.Labels{"group": "gostress", "gostress_name": name, "gostress_category": tokens[0]}
-	ExpectedRpsGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+	for label, value := range extra {
+		labels[label] = value
+	}
+	m := &Metrics{
+		Registry:      prometheus.NewRegistry(),
+		Latency:       NewLatencyRecorder(),
+		Phases:        NewLatencyRecorder(),
+		ErrorClasses:  NewErrorRecorder(),
+		Saturation:    NewSaturationTracker(),
+		Events:        NewEventLog(),
+		sentRate:      newSlidingRate(rateWindow),
+		completedRate: newSlidingRate(rateWindow),
+	}
+	m.Registry.MustRegister(prometheus.NewGoCollector())
+	m.Registry.MustRegister(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
+
+	m.ExpectedRpsGauge = prometheus.NewGauge(prometheus.GaugeOpts{
 		Name:        "gostress_expected_rps",
 		Help:        "gostress expected rps",
 		ConstLabels: labels,
 	})
-	prometheus.MustRegister(ExpectedRpsGauge)
+	m.Registry.MustRegister(m.ExpectedRpsGauge)
 
-	ExpectedWorkersGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+	m.ExpectedWorkersGauge = prometheus.NewGauge(prometheus.GaugeOpts{
 		Name:        "gostress_expected_workers",
 		Help:        "gostress expected workers",
 		ConstLabels: labels,
 	})
-	prometheus.MustRegister(ExpectedWorkersGauge)
+	m.Registry.MustRegister(m.ExpectedWorkersGauge)
 
-	CurrentWorkersGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+	m.CurrentWorkersGauge = prometheus.NewGauge(prometheus.GaugeOpts{
 		Name:        "gostress_current_workers",
 		Help:        "gostress current workers",
 		ConstLabels: labels,
 	})
-	prometheus.MustRegister(CurrentWorkersGauge)
+	m.Registry.MustRegister(m.CurrentWorkersGauge)
 
-	SentRequestCounter = prometheus.NewCounter(prometheus.CounterOpts{
+	m.SentRequestCounter = prometheus.NewCounter(prometheus.CounterOpts{
 		Name:        "gostress_sent_request_counter",
 		Help:        "gostress sent request counter",
 		ConstLabels: labels,
 	})
-	prometheus.MustRegister(SentRequestCounter)
+	m.Registry.MustRegister(m.SentRequestCounter)
 
-	SkippedRequestCounter = prometheus.NewCounter(prometheus.CounterOpts{
+	m.SkippedRequestCounter = prometheus.NewCounter(prometheus.CounterOpts{
 		Name:        "gostress_skipped_request_counter",
 		Help:        "gostress skipped request counter",
 		ConstLabels: labels,
 	})
-	prometheus.MustRegister(SkippedRequestCounter)
+	m.Registry.MustRegister(m.SkippedRequestCounter)
 
-	ErrorsCounter = prometheus.NewCounter(prometheus.CounterOpts{
+	m.ErrorsCounter = prometheus.NewCounter(prometheus.CounterOpts{
 		Name:        "gostress_errors_request_counter",
 		Help:        "gostress errors request counter",
 		ConstLabels: labels,
 	})
-	prometheus.MustRegister(ErrorsCounter)
+	m.Registry.MustRegister(m.ErrorsCounter)
 
-	RequestLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	m.RequestLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
 		Name:        "gostress_request_latency",
 		Help:        "gostress request latency",
 		ConstLabels: labels,
-		Buckets:     []float64{0.001, 0.005, 0.01, 0.02, 0.05, 0.1, 0.2, 0.3, 0.5, 0.7, 1.0, 5.0},
-	}, []string{"status"})
-	prometheus.MustRegister(RequestLatency)
+		Buckets:     latencyBuckets,
+	}, []string{"stage", "stage_name", "status"})
+	m.Registry.MustRegister(m.RequestLatency)
+
+	m.PhaseLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:        "gostress_phase_latency",
+		Help:        "gostress latency of the named request phases",
+		ConstLabels: labels,
+		Buckets:     latencyBuckets,
+	}, []string{"stage", "stage_name", "phase"})
+	m.Registry.MustRegister(m.PhaseLatency)
+
+	m.AchievedRpsGauge = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name:        "gostress_achieved_rps",
+		Help:        "gostress achieved rps (sent requests over sliding window)",
+		ConstLabels: labels,
+	}, func() float64 { return m.sentRate.Rate(time.Now()) })
+	m.Registry.MustRegister(m.AchievedRpsGauge)
+
+	m.CompletedRpsGauge = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name:        "gostress_completed_rps",
+		Help:        "gostress completed rps (finished requests over sliding window)",
+		ConstLabels: labels,
+	}, func() float64 { return m.completedRate.Rate(time.Now()) })
+	m.Registry.MustRegister(m.CompletedRpsGauge)
+
+	m.SchedulingLag = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:        "gostress_scheduling_lag",
+		Help:        "gostress lag between intended and actual request trigger time",
+		ConstLabels: labels,
+		Buckets:     prometheus.ExponentialBuckets(0.0001, 4, 8),
+	})
+	m.Registry.MustRegister(m.SchedulingLag)
+
+	generator := func(name, help string, value func(sample SaturationSample) float64) {
+		m.Registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        name,
+			Help:        help,
+			ConstLabels: labels,
+		}, func() float64 {
+			sample, _ := m.Saturation.Last()
+			return value(sample)
+		}))
+	}
+	generator("gostress_generator_cpu_usage", "gostress generator cpu usage (fraction of available cpu)", func(s SaturationSample) float64 { return s.CpuUsage })
+	generator("gostress_generator_gc_pause_fraction", "gostress generator fraction of time spent in gc pauses", func(s SaturationSample) float64 { return s.GcPauseFraction })
+	generator("gostress_generator_goroutines", "gostress generator goroutines count", func(s SaturationSample) float64 { return float64(s.Goroutines) })
+	generator("gostress_generator_saturated", "gostress generator saturation flag", func(s SaturationSample) float64 {
+		if s.Saturated() {
+			return 1
+		}
+		return 0
+	})
+
+	m.SetStage(Stage{})
+	return m
+}
+
+// ObserveTrigger records attempt to trigger request which was intended to be triggered lag time ago
+func (m *Metrics) ObserveTrigger(lag time.Duration, sent bool) {
+	m.SchedulingLag.Observe(lag.Seconds())
+	m.Saturation.ObserveLag(lag)
+	if sent {
+		m.SentRequestCounter.Inc()
+		m.sentRate.Add(time.Now())
+	} else {
+		m.SkippedRequestCounter.Inc()
+	}
+}
+
+// SetStage sets the stage which will be attached to all requests triggered from now on
+func (m *Metrics) SetStage(stage Stage) { m.stage.Store(stage) }
+
+func (m *Metrics) Stage() Stage { return m.stage.Load().(Stage) }
+
+// ResetRates restarts sliding windows of achieved and completed rps at the beginning of the schedule
+func (m *Metrics) ResetRates() {
+	now := time.Now()
+	m.sentRate.Reset(now)
+	m.completedRate.Reset(now)
+}
+
+func (m *Metrics) ObserveLatency(stage Stage, status string, latency time.Duration) {
+	m.RequestLatency.WithLabelValues(strconv.Itoa(stage.Index), stage.Name, status).Observe(latency.Seconds())
+	m.completedRate.Add(time.Now())
+	m.Latency.Record(stage, status, latency)
+}
+
+// ObservePhase records duration of the named phase of the request triggered during the given stage
+func (m *Metrics) ObservePhase(stage Stage, phase string, latency time.Duration) {
+	m.PhaseLatency.WithLabelValues(strconv.Itoa(stage.Index), stage.Name, phase).Observe(latency.Seconds())
+	m.Phases.Record(stage, phase, latency)
+}
+
+func metricValue(metric prometheus.Metric) float64 {
+	var m io_prometheus_client.Metric
+	if err := metric.Write(&m); err != nil {
+		return 0
+	}
+	if counter := m.GetCounter(); counter != nil {
+		return counter.GetValue()
+	}
+	return m.GetGauge().GetValue()
 }
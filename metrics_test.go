@@ -0,0 +1,52 @@
+package gostress
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestIsolatedMetrics(t *testing.T) {
+	first := NewMetrics(t.Name(), nil)
+	second := NewMetrics(t.Name(), nil)
+	first.SentRequestCounter.Add(3)
+	second.SentRequestCounter.Add(5)
+
+	count := func(m *Metrics) float64 {
+		families, err := m.Registry.Gather()
+		require.Nil(t, err)
+		for _, family := range families {
+			if family.GetName() == "gostress_sent_request_counter" {
+				return family.GetMetric()[0].GetCounter().GetValue()
+			}
+		}
+		return 0
+	}
+	assert.Equal(t, float64(3), count(first))
+	assert.Equal(t, float64(5), count(second))
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	logger := zaptest.NewLogger(t).Sugar()
+	f := func(ctx RequestContext) error { return nil }
+	options := GoStressOptions{Schedule: LoadSchedule{{Rps: 1, Workers: 1, Duration: time.Second}}}
+	first, shutdownFirst := NewStress(t.Name(), options, logger, t, f)
+	defer shutdownFirst()
+	second, shutdownSecond := NewStress(t.Name(), options, logger, t, f)
+	defer shutdownSecond()
+	assert.NotZero(t, first.MetricsPort)
+	assert.NotEqual(t, first.MetricsPort, second.MetricsPort)
+
+	first.Metrics.SentRequestCounter.Add(7)
+	response, err := http.Get(fmt.Sprintf("http://127.0.0.1:%v/metrics", first.MetricsPort))
+	require.Nil(t, err)
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	require.Nil(t, err)
+	assert.Contains(t, string(body), "gostress_sent_request_counter")
+}
@@ -0,0 +1,391 @@
+package gostress
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	OtlpOptions struct {
+		// Endpoint is a base url of the OTLP/HTTP receiver (for example http://otel-collector:4318)
+		Endpoint string
+		Headers  map[string]string `report:"secret"`
+		// ServiceName is attached to all exported data as service.name resource attribute (default "gostress")
+		ServiceName string
+		// Traces enables export of the span for every StressFn call
+		// Span context is placed on RequestContext.Ctx, so spans of instrumented clients become its children
+		Traces bool
+	}
+
+	otlpClient struct {
+		endpoint string
+		headers  map[string]string
+		service  string
+		client   *http.Client
+	}
+
+	// OtlpMetricsExporter sends stress metrics to the OTLP/HTTP receiver in JSON encoding
+	OtlpMetricsExporter struct {
+		otlpClient
+		gatherer  prometheus.Gatherer
+		startTime time.Time
+	}
+
+	// OtlpTracer records span for every StressFn call and exports them to the OTLP/HTTP receiver in batches
+	OtlpTracer struct {
+		otlpClient
+		lock    sync.Mutex
+		spans   []otlpSpan
+		dropped int64
+		flush   chan struct{}
+	}
+
+	otlpAttribute struct {
+		Key   string       `json:"key"`
+		Value otlpAnyValue `json:"value"`
+	}
+	otlpAnyValue struct {
+		StringValue *string `json:"stringValue,omitempty"`
+		IntValue    *string `json:"intValue,omitempty"`
+	}
+	otlpSpan struct {
+		TraceId           string          `json:"traceId"`
+		SpanId            string          `json:"spanId"`
+		Name              string          `json:"name"`
+		Kind              int             `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes"`
+		Status            otlpStatus      `json:"status"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpDataPoint struct {
+		Attributes        []otlpAttribute `json:"attributes"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		TimeUnixNano      string          `json:"timeUnixNano"`
+		AsDouble          *float64        `json:"asDouble,omitempty"`
+		Count             string          `json:"count,omitempty"`
+		Sum               *float64        `json:"sum,omitempty"`
+		BucketCounts      []string        `json:"bucketCounts,omitempty"`
+		ExplicitBounds    []float64       `json:"explicitBounds,omitempty"`
+	}
+	otlpMetric struct {
+		Name      string    `json:"name"`
+		Gauge     *otlpData `json:"gauge,omitempty"`
+		Sum       *otlpData `json:"sum,omitempty"`
+		Histogram *otlpData `json:"histogram,omitempty"`
+	}
+	otlpData struct {
+		DataPoints             []otlpDataPoint `json:"dataPoints"`
+		AggregationTemporality int             `json:"aggregationTemporality,omitempty"`
+		IsMonotonic            bool            `json:"isMonotonic,omitempty"`
+	}
+)
+
+const (
+	otlpSpanKindClient        = 3
+	otlpStatusOk              = 1
+	otlpStatusError           = 2
+	otlpTemporalityCumulative = 2
+	otlpMaxSpansBatch         = 4096
+	// otlpMaxBufferedSpans bounds memory of the tracer when receiver is slow or unavailable
+	otlpMaxBufferedSpans       = 16 * otlpMaxSpansBatch
+	otlpTracesFlushingInterval = time.Second
+)
+
+func stringAttribute(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpAnyValue{StringValue: &value}}
+}
+
+func intAttribute(key string, value int64) otlpAttribute {
+	encoded := strconv.FormatInt(value, 10)
+	return otlpAttribute{Key: key, Value: otlpAnyValue{IntValue: &encoded}}
+}
+
+func unixNano(t time.Time) string { return strconv.FormatInt(t.UnixNano(), 10) }
+
+func newOtlpClient(options OtlpOptions) otlpClient {
+	service := options.ServiceName
+	if service == "" {
+		service = "gostress"
+	}
+	return otlpClient{
+		endpoint: strings.TrimSuffix(options.Endpoint, "/"),
+		headers:  options.Headers,
+		service:  service,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (c otlpClient) resource() map[string]any {
+	return map[string]any{"attributes": []otlpAttribute{stringAttribute("service.name", c.service)}}
+}
+
+func (c otlpClient) post(ctx context.Context, path string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("unable to serialize otlp payload: %w", err)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("unable to create otlp request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for name, value := range c.headers {
+		request.Header.Set(name, value)
+	}
+	response, err := c.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("otlp request to %v failed: %w", path, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("otlp request to %v failed with status %v: %s", path, response.StatusCode, message)
+	}
+	return nil
+}
+
+func NewOtlpMetricsExporter(options OtlpOptions, gatherer prometheus.Gatherer) *OtlpMetricsExporter {
+	return &OtlpMetricsExporter{otlpClient: newOtlpClient(options), gatherer: gatherer, startTime: time.Now()}
+}
+
+func (e *OtlpMetricsExporter) Name() string { return fmt.Sprintf("otlp(%v)", e.endpoint) }
+
+func (e *OtlpMetricsExporter) Export(ctx context.Context) error {
+	families, err := e.gatherer.Gather()
+	if err != nil {
+		return fmt.Errorf("unable to gather metrics: %w", err)
+	}
+	metrics := convertFamilies(families, e.startTime, time.Now())
+	return e.post(ctx, "/v1/metrics", map[string]any{
+		"resourceMetrics": []map[string]any{{
+			"resource":     e.resource(),
+			"scopeMetrics": []map[string]any{{"scope": map[string]string{"name": "gostress"}, "metrics": metrics}},
+		}},
+	})
+}
+
+// convertFamilies converts prometheus metric families into OTLP metrics with cumulative temporality
+func convertFamilies(families []*io_prometheus_client.MetricFamily, start, now time.Time) []otlpMetric {
+	metrics := make([]otlpMetric, 0, len(families))
+	for _, family := range families {
+		gauge, sum, histogram := &otlpData{}, &otlpData{AggregationTemporality: otlpTemporalityCumulative, IsMonotonic: true}, &otlpData{AggregationTemporality: otlpTemporalityCumulative}
+		for _, metric := range family.GetMetric() {
+			point := otlpDataPoint{StartTimeUnixNano: unixNano(start), TimeUnixNano: unixNano(now), Attributes: make([]otlpAttribute, 0)}
+			for _, label := range metric.GetLabel() {
+				point.Attributes = append(point.Attributes, stringAttribute(label.GetName(), label.GetValue()))
+			}
+			switch {
+			case metric.GetCounter() != nil:
+				value := metric.GetCounter().GetValue()
+				point.AsDouble = &value
+				sum.DataPoints = append(sum.DataPoints, point)
+			case metric.GetGauge() != nil:
+				value := metric.GetGauge().GetValue()
+				point.AsDouble = &value
+				gauge.DataPoints = append(gauge.DataPoints, point)
+			case metric.GetUntyped() != nil:
+				value := metric.GetUntyped().GetValue()
+				point.AsDouble = &value
+				gauge.DataPoints = append(gauge.DataPoints, point)
+			case metric.GetHistogram() != nil:
+				h := metric.GetHistogram()
+				value, previous := h.GetSampleSum(), uint64(0)
+				point.Sum, point.Count = &value, strconv.FormatUint(h.GetSampleCount(), 10)
+				for _, bucket := range h.GetBucket() {
+					point.ExplicitBounds = append(point.ExplicitBounds, bucket.GetUpperBound())
+					point.BucketCounts = append(point.BucketCounts, strconv.FormatUint(bucket.GetCumulativeCount()-previous, 10))
+					previous = bucket.GetCumulativeCount()
+				}
+				point.BucketCounts = append(point.BucketCounts, strconv.FormatUint(h.GetSampleCount()-previous, 10))
+				histogram.DataPoints = append(histogram.DataPoints, point)
+			}
+		}
+		metric := otlpMetric{Name: family.GetName()}
+		switch {
+		case len(sum.DataPoints) > 0:
+			metric.Sum = sum
+		case len(gauge.DataPoints) > 0:
+			metric.Gauge = gauge
+		case len(histogram.DataPoints) > 0:
+			metric.Histogram = histogram
+		default:
+			continue
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics
+}
+
+func NewOtlpTracer(options OtlpOptions) *OtlpTracer {
+	return &OtlpTracer{otlpClient: newOtlpClient(options), flush: make(chan struct{}, 1)}
+}
+
+func randomId(size int) []byte {
+	id := make([]byte, size)
+	_, _ = rand.Read(id)
+	return id
+}
+
+// Wrap returns StressFn which records span for every call of f with id, worker, stage, status and operation attributes
+// Stage is the one during which request was triggered, even if the request finishes in the next stage
+func (t *OtlpTracer) Wrap(operation string, f StressFn) StressFn {
+	return func(ctx RequestContext) error {
+		var traceId trace.TraceID
+		var spanId trace.SpanID
+		copy(traceId[:], randomId(len(traceId)))
+		copy(spanId[:], randomId(len(spanId)))
+		spanContext := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceId, SpanID: spanId, TraceFlags: trace.FlagsSampled})
+		ctx.Ctx = trace.ContextWithSpanContext(ctx.Ctx, spanContext)
+		stage := ctx.stage
+		startTime := time.Now()
+		err := f(ctx)
+		span := otlpSpan{
+			TraceId:           traceId.String(),
+			SpanId:            spanId.String(),
+			Name:              operation,
+			Kind:              otlpSpanKindClient,
+			StartTimeUnixNano: unixNano(startTime),
+			EndTimeUnixNano:   unixNano(time.Now()),
+			Attributes: []otlpAttribute{
+				intAttribute("gostress.id", int64(ctx.Id)),
+				intAttribute("gostress.worker", int64(ctx.Worker)),
+				intAttribute("gostress.stage", int64(stage.Index)),
+				stringAttribute("gostress.stage_name", stage.Name),
+				stringAttribute("gostress.operation", operation),
+				stringAttribute("gostress.status", "success"),
+			},
+			Status: otlpStatus{Code: otlpStatusOk},
+		}
+		if err != nil {
+			span.Attributes[len(span.Attributes)-1] = stringAttribute("gostress.status", "error")
+			span.Status = otlpStatus{Code: otlpStatusError, Message: err.Error()}
+		}
+		t.record(span)
+		return err
+	}
+}
+
+func (t *OtlpTracer) record(span otlpSpan) {
+	t.lock.Lock()
+	if len(t.spans) >= otlpMaxBufferedSpans {
+		t.dropped++
+		t.lock.Unlock()
+		return
+	}
+	t.spans = append(t.spans, span)
+	full := len(t.spans) >= otlpMaxSpansBatch
+	t.lock.Unlock()
+	if full {
+		select {
+		case t.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// requeue returns spans which failed to export back to the buffer, so they are retried by the next flush
+func (t *OtlpTracer) requeue(spans []otlpSpan) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	free := otlpMaxBufferedSpans - len(t.spans)
+	if free < 0 {
+		free = 0
+	}
+	if len(spans) > free {
+		t.dropped += int64(len(spans) - free)
+		spans = spans[:free]
+	}
+	t.spans = append(append(make([]otlpSpan, 0, len(spans)+len(t.spans)), spans...), t.spans...)
+}
+
+// Dropped returns number of spans which were not exported because buffer was full
+func (t *OtlpTracer) Dropped() int64 {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.dropped
+}
+
+// Flush exports all recorded spans, batches which failed to export are kept for the next flush
+func (t *OtlpTracer) Flush(ctx context.Context) error {
+	t.lock.Lock()
+	spans := t.spans
+	t.spans = nil
+	t.lock.Unlock()
+	failed, errs := make([]otlpSpan, 0), make([]error, 0)
+	for len(spans) > 0 {
+		batch := spans
+		if len(batch) > otlpMaxSpansBatch {
+			batch = batch[:otlpMaxSpansBatch]
+		}
+		spans = spans[len(batch):]
+		err := t.post(ctx, "/v1/traces", map[string]any{
+			"resourceSpans": []map[string]any{{
+				"resource":   t.resource(),
+				"scopeSpans": []map[string]any{{"scope": map[string]string{"name": "gostress"}, "spans": batch}},
+			}},
+		})
+		if err != nil {
+			failed, errs = append(failed, batch...), append(errs, err)
+		}
+	}
+	if len(failed) > 0 {
+		t.requeue(failed)
+	}
+	return errors.Join(errs...)
+}
+
+// Start exports recorded spans every second (or earlier when batch is full) until returned function is called
+func (t *OtlpTracer) Start(logger *zap.SugaredLogger) func() {
+	finish, finished := make(chan struct{}), make(chan struct{})
+	reported := int64(0)
+	flush := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := t.Flush(ctx); err != nil {
+			logger.Errorf("unable to export spans: %v", err)
+		}
+		if dropped := t.Dropped(); dropped > reported {
+			logger.Warnf("dropped %v spans because export doesn't keep up (%v in total)", dropped-reported, dropped)
+			reported = dropped
+		}
+	}
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(otlpTracesFlushingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				flush()
+			case <-t.flush:
+				flush()
+			case <-finish:
+				flush()
+				return
+			}
+		}
+	}()
+	return func() {
+		close(finish)
+		<-finished
+	}
+}
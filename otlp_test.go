@@ -0,0 +1,81 @@
+package gostress
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap/zaptest"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestOtlpExport(t *testing.T) {
+	lock, payloads := sync.Mutex{}, make(map[string][]map[string]any)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		require.Nil(t, json.NewDecoder(r.Body).Decode(&payload))
+		lock.Lock()
+		payloads[r.URL.Path] = append(payloads[r.URL.Path], payload)
+		lock.Unlock()
+	}))
+	defer server.Close()
+
+	options := OtlpOptions{Endpoint: server.URL, Traces: true}
+	metrics := NewMetrics(t.Name(), nil)
+	tracer := NewOtlpTracer(options)
+	stop := tracer.Start(zaptest.NewLogger(t).Sugar())
+	f := tracer.Wrap("operation", func(ctx RequestContext) error {
+		assert.True(t, trace.SpanContextFromContext(ctx.Ctx).IsValid())
+		return nil
+	})
+	// span is attributed to the stage of the trigger, not to the current one
+	metrics.SetStage(Stage{Index: 2, Name: "peak"})
+	require.Nil(t, f(RequestContext{Id: 1, Worker: 2, Ctx: context.Background(), stage: Stage{Index: 1, Name: "warmup"}}))
+	stop()
+	require.Nil(t, NewOtlpMetricsExporter(options, metrics.Registry).Export(context.Background()))
+
+	require.Len(t, payloads["/v1/traces"], 1)
+	traces, _ := json.Marshal(payloads["/v1/traces"][0])
+	assert.Contains(t, string(traces), `"name":"operation"`)
+	assert.Contains(t, string(traces), `"key":"gostress.worker","value":{"intValue":"2"}`)
+	assert.Contains(t, string(traces), `"key":"gostress.stage_name","value":{"stringValue":"warmup"}`)
+	require.Len(t, payloads["/v1/metrics"], 1)
+	exported, _ := json.Marshal(payloads["/v1/metrics"][0])
+	assert.Contains(t, string(exported), `"name":"gostress_sent_request_counter"`)
+}
+
+func TestOtlpTracerRetry(t *testing.T) {
+	lock, failures, exported := sync.Mutex{}, 1, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		exported++
+	}))
+	defer server.Close()
+
+	tracer := NewOtlpTracer(OtlpOptions{Endpoint: server.URL, Traces: true})
+	for i := 0; i < otlpMaxSpansBatch+1; i++ {
+		tracer.record(otlpSpan{Name: "operation"})
+	}
+	assert.NotNil(t, tracer.Flush(context.Background()))
+	assert.Equal(t, 1, exported)
+	require.Len(t, tracer.spans, otlpMaxSpansBatch)
+	require.Nil(t, tracer.Flush(context.Background()))
+	assert.Equal(t, 2, exported)
+	assert.Empty(t, tracer.spans)
+
+	for i := 0; i < otlpMaxBufferedSpans+3; i++ {
+		tracer.record(otlpSpan{Name: "operation"})
+	}
+	assert.Len(t, tracer.spans, otlpMaxBufferedSpans)
+	assert.Equal(t, int64(3), tracer.Dropped())
+}
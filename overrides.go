@@ -0,0 +1,164 @@
+package gostress
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	GoStressScheduleEnv           = "GOSTRESS_SCHEDULE"
+	GoStressRpsMultiplierEnv      = "GOSTRESS_RPS_MULTIPLIER"
+	GoStressDurationMultiplierEnv = "GOSTRESS_DURATION_MULTIPLIER"
+	GoStressReportEnv             = "GOSTRESS_REPORT"
+	GoStressDryRunEnv             = "GOSTRESS_DRY_RUN"
+)
+
+// Overrides change options of the existing test without recompilation:
+//
+//	go test -run X -args -gostress.schedule=10:1:30s -gostress.rps-multiplier=2 -gostress.report=json
+//
+// Every flag can be set with the environment variable as well (flags take precedence)
+// Profile is applied to the schedule first, so schedule and multipliers overrides take precedence over it
+type Overrides struct {
+	// Schedule replaces schedule of the test
+	Schedule LoadSchedule
+	// RpsMultiplier scales rps of every stage (workers are kept as is)
+	RpsMultiplier float64
+	// DurationMultiplier scales duration of every stage
+	DurationMultiplier float64
+	// Report replaces report formats of the test
+	Report []string
+	// Profile selects profile of the run
+	Profile string
+	// DryRun prints the planned schedule instead of running the load
+	DryRun bool
+}
+
+var (
+	scheduleFlag           = flag.String("gostress.schedule", "", `override schedule in the "[name=]rps:workers:duration,..." form (env `+GoStressScheduleEnv+`)`)
+	rpsMultiplierFlag      = flag.String("gostress.rps-multiplier", "", "multiply rps of every stage (env "+GoStressRpsMultiplierEnv+")")
+	durationMultiplierFlag = flag.String("gostress.duration-multiplier", "", "multiply duration of every stage (env "+GoStressDurationMultiplierEnv+")")
+	profileFlag            = flag.String("gostress.profile", "", "select profile of the run: smoke, load, stress, spike, soak or custom one (env "+GoStressProfileEnv+")")
+	dryRunFlag             = flag.String("gostress.dry-run", "", "print the planned schedule without calling the stress function (env "+GoStressDryRunEnv+")")
+	reportFlag             = flag.String("gostress.report", "", `override report formats with comma-separated "format[:path]" list (env `+GoStressReportEnv+`)`)
+)
+
+func overrideValue(value *string, env string) string {
+	if *value != "" {
+		return *value
+	}
+	return os.Getenv(env)
+}
+
+// LoadOverrides reads overrides from the gostress.* flags and environment variables
+func LoadOverrides() (Overrides, error) {
+	overrides := Overrides{}
+	if schedule := overrideValue(scheduleFlag, GoStressScheduleEnv); schedule != "" {
+		parsed, err := ParseLoadSchedule(schedule)
+		if err != nil {
+			return overrides, fmt.Errorf("invalid schedule override: %w", err)
+		}
+		overrides.Schedule = parsed
+	}
+	multiplier := func(value string) (float64, error) {
+		if value == "" {
+			return 0, nil
+		}
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err == nil && parsed <= 0 {
+			err = fmt.Errorf("multiplier must be positive")
+		}
+		return parsed, err
+	}
+	var err error
+	if overrides.RpsMultiplier, err = multiplier(overrideValue(rpsMultiplierFlag, GoStressRpsMultiplierEnv)); err != nil {
+		return overrides, fmt.Errorf("invalid rps multiplier override: %w", err)
+	}
+	if overrides.DurationMultiplier, err = multiplier(overrideValue(durationMultiplierFlag, GoStressDurationMultiplierEnv)); err != nil {
+		return overrides, fmt.Errorf("invalid duration multiplier override: %w", err)
+	}
+	if report := overrideValue(reportFlag, GoStressReportEnv); report != "" {
+		overrides.Report = strings.Split(report, ",")
+	}
+	overrides.Profile = overrideValue(profileFlag, GoStressProfileEnv)
+	if dryRun := overrideValue(dryRunFlag, GoStressDryRunEnv); dryRun != "" {
+		if overrides.DryRun, err = strconv.ParseBool(dryRun); err != nil {
+			return overrides, fmt.Errorf("invalid dry run override: %w", err)
+		}
+	}
+	return overrides, nil
+}
+
+func (o Overrides) Empty() bool {
+	return len(o.Schedule) == 0 && o.RpsMultiplier == 0 && o.DurationMultiplier == 0 && len(o.Report) == 0 && o.Profile == "" && !o.DryRun
+}
+
+// Apply returns copy of the options with overrides applied
+func (o Overrides) Apply(options GoStressOptions) GoStressOptions {
+	schedule := options.Schedule
+	if len(o.Schedule) > 0 {
+		schedule = o.Schedule
+	}
+	options.Schedule = make(LoadSchedule, len(schedule))
+	for i, stage := range schedule {
+		if o.RpsMultiplier > 0 {
+			stage.Rps = int(math.Round(float64(stage.Rps) * o.RpsMultiplier))
+		}
+		if o.DurationMultiplier > 0 {
+			stage.Duration = time.Duration(float64(stage.Duration) * o.DurationMultiplier)
+		}
+		options.Schedule[i] = stage
+	}
+	if len(o.Report) > 0 {
+		options.Report.Formats = o.Report
+	}
+	if o.Profile != "" {
+		options.Profile = o.Profile
+	}
+	if o.DryRun {
+		options.DryRun = true
+	}
+	return options
+}
+
+// Args returns command line flags which reproduce overrides in another process
+func (o Overrides) Args() []string {
+	args := make([]string, 0)
+	if len(o.Schedule) > 0 {
+		stages := make([]string, 0, len(o.Schedule))
+		for _, stage := range o.Schedule {
+			description := fmt.Sprintf("%v:%v:%v", stage.Rps, stage.Workers, stage.Duration)
+			if stage.Name != "" {
+				description = stage.Name + "=" + description
+			}
+			stages = append(stages, description)
+		}
+		args = append(args, "-gostress.schedule="+strings.Join(stages, ","))
+	}
+	if o.RpsMultiplier > 0 {
+		args = append(args, "-gostress.rps-multiplier="+strconv.FormatFloat(o.RpsMultiplier, 'g', -1, 64))
+	}
+	if o.DurationMultiplier > 0 {
+		args = append(args, "-gostress.duration-multiplier="+strconv.FormatFloat(o.DurationMultiplier, 'g', -1, 64))
+	}
+	if len(o.Report) > 0 {
+		args = append(args, "-gostress.report="+strings.Join(o.Report, ","))
+	}
+	if o.Profile != "" {
+		args = append(args, "-gostress.profile="+o.Profile)
+	}
+	if o.DryRun {
+		args = append(args, "-gostress.dry-run=true")
+	}
+	return args
+}
+
+// shellQuote quotes argument for the bash command line
+func shellQuote(arg string) string {
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
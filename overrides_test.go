@@ -0,0 +1,83 @@
+package gostress
+
+import (
+	"flag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"testing"
+	"time"
+)
+
+func TestOverrides(t *testing.T) {
+	t.Setenv(GoStressScheduleEnv, "1:1:1s")
+	t.Setenv(GoStressRpsMultiplierEnv, "2")
+	t.Setenv(GoStressDurationMultiplierEnv, "0.1")
+	require.Nil(t, flag.Set("gostress.schedule", "warmup=10:1:10s,100:4:1m"))
+	require.Nil(t, flag.Set("gostress.report", "json,markdown:report.md"))
+	t.Cleanup(func() {
+		_ = flag.Set("gostress.schedule", "")
+		_ = flag.Set("gostress.report", "")
+	})
+
+	overrides, err := LoadOverrides()
+	require.Nil(t, err)
+	assert.False(t, overrides.Empty())
+	options := overrides.Apply(GoStressOptions{WorkerTimeout: time.Second})
+	assert.Equal(t, time.Second, options.WorkerTimeout)
+	assert.Equal(t, LoadSchedule{
+		{Name: "warmup", Rps: 20, Workers: 1, Duration: time.Second},
+		{Rps: 200, Workers: 4, Duration: 6 * time.Second},
+	}, options.Schedule)
+	assert.Equal(t, []string{"json", "markdown:report.md"}, options.Report.Formats)
+	assert.Equal(t, []string{
+		"-gostress.schedule=warmup=10:1:10s,100:4:1m0s",
+		"-gostress.rps-multiplier=2",
+		"-gostress.duration-multiplier=0.1",
+		"-gostress.report=json,markdown:report.md",
+	}, overrides.Args())
+
+	t.Setenv(GoStressRpsMultiplierEnv, "-1")
+	_, err = LoadOverrides()
+	assert.NotNil(t, err)
+}
+
+func TestOverridesKeepOptions(t *testing.T) {
+	schedule := LoadSchedule{{Rps: 10, Workers: 1, Duration: time.Second}}
+	options := Overrides{RpsMultiplier: 3}.Apply(GoStressOptions{Schedule: schedule})
+	assert.Equal(t, 30, options.Schedule[0].Rps)
+	assert.Equal(t, 10, schedule[0].Rps)
+	assert.True(t, Overrides{}.Empty())
+	assert.Equal(t, `'it'\''s'`, shellQuote("it's"))
+}
+
+func TestOverridesWithProfile(t *testing.T) {
+	logger := zaptest.NewLogger(t).Sugar()
+	options := GoStressOptions{Schedule: LoadSchedule{{Rps: 10, Workers: 2, Duration: time.Minute}}}
+
+	soak, labels, err := resolveOptions(options, Overrides{Profile: "soak", DurationMultiplier: 0.01}, logger)
+	require.Nil(t, err)
+	assert.Equal(t, LoadSchedule{{Name: "soak", Rps: 10, Workers: 2, Duration: 144 * time.Second}}, soak.Schedule)
+	assert.Equal(t, "soak", soak.Profile)
+	assert.Equal(t, "soak", labels["gostress_profile"])
+
+	schedule := LoadSchedule{{Rps: 4, Workers: 1, Duration: time.Second}}
+	smoke, _, err := resolveOptions(options, Overrides{Profile: "smoke", Schedule: schedule, RpsMultiplier: 2}, logger)
+	require.Nil(t, err)
+	assert.Equal(t, LoadSchedule{{Rps: 8, Workers: 1, Duration: time.Second}}, smoke.Schedule)
+	assert.True(t, smoke.Thresholds.FailOnErrors)
+}
+
+func TestResolveOptionsErrors(t *testing.T) {
+	logger := zaptest.NewLogger(t).Sugar()
+	_, _, err := resolveOptions(GoStressOptions{}, Overrides{}, logger)
+	assert.NotNil(t, err)
+	_, _, err = resolveOptions(GoStressOptions{Profile: "load"}, Overrides{}, logger)
+	assert.ErrorContains(t, err, "profile load")
+	_, _, err = resolveOptions(GoStressOptions{Schedule: LoadSchedule{{Rps: 1, Workers: 1, Duration: time.Second}}}, Overrides{Profile: "unknown"}, logger)
+	assert.ErrorContains(t, err, "unknown")
+	// schedule override provides the base schedule for the profile
+	options, _, err := resolveOptions(GoStressOptions{Profile: "stress"}, Overrides{Schedule: LoadSchedule{{Rps: 1, Workers: 1, Duration: time.Second}}}, logger)
+	require.Nil(t, err)
+	assert.Len(t, options.Schedule, 1)
+}
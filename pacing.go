@@ -0,0 +1,75 @@
+package gostress
+
+import (
+	"sync"
+	"time"
+)
+
+// slidingRate counts events in per-second buckets and reports average rate over the last complete seconds of the window
+type slidingRate struct {
+	lock    sync.Mutex
+	start   int64
+	counts  []int64
+	seconds []int64
+}
+
+func newSlidingRate(window int) *slidingRate {
+	return &slidingRate{start: time.Now().Unix(), counts: make([]int64, window+1), seconds: make([]int64, window+1)}
+}
+
+// Reset drops counted events and starts the window from now, so the rate isn't diluted by the idle time before the run
+func (r *slidingRate) Reset(now time.Time) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.start = now.Unix()
+	for i := range r.counts {
+		r.counts[i], r.seconds[i] = 0, 0
+	}
+}
+
+func (r *slidingRate) Add(now time.Time) {
+	second := now.Unix()
+	i := second % int64(len(r.counts))
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.seconds[i] != second {
+		r.seconds[i], r.counts[i] = second, 0
+	}
+	r.counts[i]++
+}
+
+func (r *slidingRate) Rate(now time.Time) float64 {
+	second := now.Unix()
+	window := int64(len(r.counts) - 1)
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if elapsed := second - r.start; elapsed < window {
+		window = elapsed
+	}
+	if window <= 0 {
+		return 0
+	}
+	total := int64(0)
+	for i, s := range r.seconds {
+		if s >= second-window && s < second {
+			total += r.counts[i]
+		}
+	}
+	return float64(total) / float64(window)
+}
+
+// pacingWarning tracks whether achieved rps stays below expected and fires once per every checks consecutive failed checks
+type pacingWarning struct {
+	tolerance float64
+	checks    int
+	below     int
+}
+
+func (w *pacingWarning) Check(expected, achieved float64) bool {
+	if expected > 0 && achieved < expected*(1-w.tolerance) {
+		w.below++
+	} else {
+		w.below = 0
+	}
+	return w.below > 0 && w.below%w.checks == 0
+}
@@ -0,0 +1,38 @@
+package gostress
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSlidingRate(t *testing.T) {
+	start := time.Unix(1000, 0)
+	r := &slidingRate{start: start.Unix(), counts: make([]int64, 4), seconds: make([]int64, 4)}
+	rates := make([]float64, 0)
+	for second := 0; second < 6; second++ {
+		now := start.Add(time.Duration(second) * time.Second)
+		rates = append(rates, r.Rate(now))
+		for i := 0; i < 10*(second+1); i++ {
+			r.Add(now.Add(time.Duration(i) * time.Millisecond))
+		}
+	}
+	rates = append(rates, r.Rate(start.Add(6*time.Second)))
+	assert.Equal(t, []float64{0, 10, 15, 20, 30, 40, 50}, rates)
+
+	r.Reset(start.Add(10 * time.Second))
+	assert.Equal(t, 0.0, r.Rate(start.Add(10*time.Second)))
+	for i := 0; i < 17; i++ {
+		r.Add(start.Add(10*time.Second + time.Duration(i)*time.Millisecond))
+	}
+	assert.Equal(t, 17.0, r.Rate(start.Add(11*time.Second)))
+}
+
+func TestPacingWarning(t *testing.T) {
+	w := pacingWarning{tolerance: 0.1, checks: 3}
+	fired := make([]bool, 0)
+	for _, achieved := range []float64{95, 80, 80, 80, 80, 80, 80, 100, 80} {
+		fired = append(fired, w.Check(100, achieved))
+	}
+	assert.Equal(t, []bool{false, false, false, true, false, false, true, false, false}, fired)
+}
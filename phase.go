@@ -0,0 +1,40 @@
+package gostress
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Span measures duration of the named phase within single request (auth, main call, response parsing, etc)
+type Span struct {
+	Phase     string
+	StartTime time.Time
+	stage     Stage
+	metrics   *Metrics
+	ended     atomic.Bool
+}
+
+// StartPhase starts measuring of the named phase; duration is recorded when End is called:
+//
+//	defer ctx.StartPhase("auth").End()
+func (r RequestContext) StartPhase(phase string) *Span {
+	return &Span{Phase: phase, StartTime: time.Now(), stage: r.stage, metrics: r.metrics}
+}
+
+// End records duration of the phase; only first call has an effect
+func (s *Span) End() time.Duration {
+	duration := time.Since(s.StartTime)
+	if s.ended.Swap(true) {
+		return duration
+	}
+	if s.metrics != nil {
+		s.metrics.ObservePhase(s.stage, s.Phase, duration)
+	}
+	return duration
+}
+
+// MeasurePhase runs f within the named phase
+func (r RequestContext) MeasurePhase(phase string, f func() error) error {
+	defer r.StartPhase(phase).End()
+	return f()
+}
@@ -0,0 +1,51 @@
+package gostress
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"testing"
+	"time"
+)
+
+func TestPhases(t *testing.T) {
+	metrics := NewMetrics(t.Name(), nil)
+	metrics.SetStage(Stage{Index: 1, Name: "peak"})
+	start := metrics.Snapshot()
+	w := NewWorker(0)
+	work := make(chan Id)
+	go w.Run(work, time.Second, metrics, zaptest.NewLogger(t).Sugar(), func(ctx RequestContext) error {
+		auth := ctx.StartPhase("auth")
+		time.Sleep(time.Millisecond)
+		auth.End()
+		auth.End()
+		return ctx.MeasurePhase("call", func() error {
+			time.Sleep(5 * time.Millisecond)
+			return nil
+		})
+	})
+	for i := 0; i < 10; i++ {
+		work <- Id(i)
+	}
+	w.Shutdown <- struct{}{}
+	<-w.Finished
+
+	phases := metrics.Phases.StageSnapshot(1)
+	require.Contains(t, phases, "auth")
+	require.Contains(t, phases, "call")
+	assert.Equal(t, int64(10), phases["auth"].Count)
+	assert.GreaterOrEqual(t, phases["call"].Min, 5*time.Millisecond)
+
+	result := metrics.Snapshot().StageResult(start)
+	assert.Equal(t, int64(10), result.Phases["call"].Count)
+
+	stat, err := PrintStat(metrics)
+	require.Nil(t, err)
+	assert.Contains(t, stat, "{phase:auth}")
+	assert.Contains(t, stat, "{phase:call}")
+}
+
+func TestPhaseWithoutMetrics(t *testing.T) {
+	span := RequestContext{}.StartPhase("auth")
+	assert.GreaterOrEqual(t, span.End(), time.Duration(0))
+}
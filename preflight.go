@@ -0,0 +1,85 @@
+package gostress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+type (
+	// PreflightOptions configures sequential execution of the stress function before the load starts,
+	// so misconfigured runs fail in seconds instead of producing a report full of errors
+	PreflightOptions struct {
+		// Requests is a number of sequential requests (preflight is disabled when zero)
+		Requests int
+		// Timeout of the single request (default is the WorkerTimeout)
+		Timeout time.Duration
+	}
+
+	// SchedulePlan describes load which the schedule will produce
+	SchedulePlan struct {
+		Schedule      LoadSchedule
+		TotalRequests int64
+		PeakRps       int
+		PeakWorkers   int
+		Duration      time.Duration
+	}
+)
+
+// RunPreflight executes f sequentially and returns all collected errors if any of the requests failed
+func RunPreflight(ctx context.Context, requests int, timeout time.Duration, logger *zap.SugaredLogger, f StressFn) error {
+	failures := make([]error, 0)
+	for i := 0; i < requests && !Finished(ctx); i++ {
+		requestCtx, cancel := context.WithTimeout(ctx, timeout)
+		startTime := time.Now()
+		err := f(RequestContext{Id: Id(-i - 1), Ctx: requestCtx, Logger: logger.With(zap.String("phase", "preflight"))})
+		cancel()
+		if err != nil {
+			logger.Errorf("preflight request %v failed in %v: %v", i+1, roundLatency(time.Since(startTime)), err)
+			failures = append(failures, fmt.Errorf("request %v: %w", i+1, err))
+		} else {
+			logger.Infof("preflight request %v succeeded in %v", i+1, roundLatency(time.Since(startTime)))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%v of %v preflight requests failed: %w", len(failures), requests, errors.Join(failures...))
+	}
+	return nil
+}
+
+// PlanSchedule computes load of the schedule; rps changes linearly between consecutive stages as in RunSchedule
+func PlanSchedule(schedule LoadSchedule) SchedulePlan {
+	plan := SchedulePlan{Schedule: schedule}
+	for i, stage := range schedule {
+		end := stage
+		if i+1 < len(schedule) {
+			end = schedule[i+1]
+		}
+		plan.TotalRequests += int64(float64(stage.Rps+end.Rps) / 2 * stage.Duration.Seconds())
+		plan.Duration += stage.Duration
+		if stage.Rps > plan.PeakRps {
+			plan.PeakRps = stage.Rps
+		}
+		if stage.Workers > plan.PeakWorkers {
+			plan.PeakWorkers = stage.Workers
+		}
+	}
+	return plan
+}
+
+func (p SchedulePlan) String() string {
+	lines := []string{
+		fmt.Sprintf("%32v: %v", "total requests", p.TotalRequests),
+		fmt.Sprintf("%32v: %v", "peak rps", p.PeakRps),
+		fmt.Sprintf("%32v: %v", "peak workers", p.PeakWorkers),
+		fmt.Sprintf("%32v: %v", "duration", p.Duration),
+		fmt.Sprintf("%32v:", "stages"),
+	}
+	for i, stage := range p.Schedule {
+		lines = append(lines, fmt.Sprintf("%32v: %v", Stage{Index: i, Name: stage.Name}, stage.String()))
+	}
+	return strings.Join(lines, "\n")
+}
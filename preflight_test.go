@@ -0,0 +1,73 @@
+package gostress
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunPreflight(t *testing.T) {
+	logger := zaptest.NewLogger(t).Sugar()
+	calls := int32(0)
+	require.Nil(t, RunPreflight(context.Background(), 3, time.Second, logger, func(ctx RequestContext) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	}))
+	assert.Equal(t, int32(3), calls)
+
+	err := RunPreflight(context.Background(), 3, time.Second, logger, func(ctx RequestContext) error {
+		if ctx.Id == -2 {
+			return nil
+		}
+		return fmt.Errorf("connection refused")
+	})
+	require.NotNil(t, err)
+	assert.Equal(t, "2 of 3 preflight requests failed: request 1: connection refused\nrequest 3: connection refused", err.Error())
+}
+
+func TestPlanSchedule(t *testing.T) {
+	plan := PlanSchedule(LoadSchedule{
+		{Name: "ramp", Rps: 0, Workers: 1, Duration: 10 * time.Second},
+		{Rps: 100, Workers: 8, Duration: time.Minute},
+	})
+	assert.Equal(t, int64(500+6000), plan.TotalRequests)
+	assert.Equal(t, 100, plan.PeakRps)
+	assert.Equal(t, 8, plan.PeakWorkers)
+	assert.Equal(t, 70*time.Second, plan.Duration)
+	assert.Contains(t, plan.String(), "stage 0 (ramp)")
+}
+
+func TestRunLocalPreflight(t *testing.T) {
+	logger := zaptest.NewLogger(t).Sugar()
+	calls := int32(0)
+	f := func(ctx RequestContext) error {
+		atomic.AddInt32(&calls, 1)
+		return fmt.Errorf("misconfigured endpoint")
+	}
+	options := GoStressOptions{
+		WorkerTimeout: time.Second,
+		Schedule:      LoadSchedule{{Rps: 100, Workers: 4, Duration: time.Minute}},
+		DryRun:        true,
+	}
+	failures := &cliFailures{logger: logger}
+	stress, shutdown := NewStress(t.Name(), options, logger, failures, f)
+	result := stress.RunLocal(context.Background())
+	shutdown()
+	assert.Equal(t, int32(0), calls)
+	assert.False(t, result.Aborted)
+	assert.False(t, failures.Failed())
+
+	options.DryRun, options.Preflight.Requests = false, 2
+	stress, shutdown = NewStress(t.Name(), options, logger, failures, f)
+	defer shutdown()
+	result = stress.RunLocal(context.Background())
+	assert.Equal(t, int32(2), calls)
+	assert.True(t, result.Aborted)
+	assert.Contains(t, result.AbortReason, "misconfigured endpoint")
+	assert.True(t, failures.Failed())
+}
@@ -0,0 +1,123 @@
+package gostress
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const GoStressProfileEnv = "GOSTRESS_PROFILE"
+
+// Profile derives schedule of the specific kind of run (smoke, spike, soak, etc) from the base schedule of the test
+type Profile struct {
+	Name        string
+	Description string
+	// Transform returns schedule of the profile, nil Transform keeps the base schedule
+	Transform func(base LoadSchedule) LoadSchedule
+	// Thresholds replace thresholds of the test when set
+	Thresholds *ThresholdOptions
+}
+
+var (
+	SmokeProfile = Profile{
+		Name:        "smoke",
+		Description: "1 rps for 10s, any error fails the run",
+		Transform: func(LoadSchedule) LoadSchedule {
+			return LoadSchedule{{Name: "smoke", Rps: 1, Workers: 1, Duration: 10 * time.Second}}
+		},
+		Thresholds: &ThresholdOptions{FailOnErrors: true},
+	}
+	LoadProfile = Profile{
+		Name:        "load",
+		Description: "base schedule as is",
+	}
+	StressProfile = Profile{
+		Name:        "stress",
+		Description: "base schedule with doubled rps and workers",
+		Transform: func(base LoadSchedule) LoadSchedule {
+			return scaleSchedule(base, 2, 2, 1)
+		},
+	}
+	SpikeProfile = Profile{
+		Name:        "spike",
+		Description: "1m at the peak of the base schedule, 30s at 5x peak, 1m of recovery at the peak",
+		Transform: func(base LoadSchedule) LoadSchedule {
+			peak := schedulePeak(base)
+			spike := LoadParams{Rps: 5 * peak.Rps, Workers: 5 * peak.Workers}
+			return LoadSchedule{
+				{Name: "before", Rps: peak.Rps, Workers: peak.Workers, Duration: time.Minute},
+				{Name: "ramp-up", Rps: peak.Rps, Workers: peak.Workers, Duration: time.Second},
+				{Name: "spike", Rps: spike.Rps, Workers: spike.Workers, Duration: 30 * time.Second},
+				{Name: "ramp-down", Rps: spike.Rps, Workers: spike.Workers, Duration: time.Second},
+				{Name: "recovery", Rps: peak.Rps, Workers: peak.Workers, Duration: time.Minute},
+			}
+		},
+	}
+	SoakProfile = Profile{
+		Name:        "soak",
+		Description: "peak of the base schedule held for 4h",
+		Transform: func(base LoadSchedule) LoadSchedule {
+			peak := schedulePeak(base)
+			return LoadSchedule{{Name: "soak", Rps: peak.Rps, Workers: peak.Workers, Duration: 4 * time.Hour}}
+		},
+	}
+
+	presetProfiles = []Profile{SmokeProfile, LoadProfile, StressProfile, SpikeProfile, SoakProfile}
+)
+
+// schedulePeak returns stage with the maximum rps
+func schedulePeak(schedule LoadSchedule) LoadParams {
+	peak := LoadParams{Rps: 1, Workers: 1}
+	for _, stage := range schedule {
+		if stage.Rps > peak.Rps {
+			peak = stage
+		}
+	}
+	return peak
+}
+
+func scaleSchedule(schedule LoadSchedule, rps, workers, duration float64) LoadSchedule {
+	scaled := make(LoadSchedule, len(schedule))
+	for i, stage := range schedule {
+		stage.Rps = int(float64(stage.Rps) * rps)
+		stage.Workers = int(float64(stage.Workers) * workers)
+		stage.Duration = time.Duration(float64(stage.Duration) * duration)
+		scaled[i] = stage
+	}
+	return scaled
+}
+
+// AvailableProfiles returns preset profiles merged with the custom ones (custom profiles replace presets with the same name)
+func AvailableProfiles(custom []Profile) []Profile {
+	profiles := make(map[string]Profile)
+	for _, profile := range append(append([]Profile{}, presetProfiles...), custom...) {
+		profiles[profile.Name] = profile
+	}
+	available := make([]Profile, 0, len(profiles))
+	for _, profile := range profiles {
+		available = append(available, profile)
+	}
+	sort.Slice(available, func(i, j int) bool { return available[i].Name < available[j].Name })
+	return available
+}
+
+func ResolveProfile(name string, custom []Profile) (Profile, error) {
+	for _, profile := range AvailableProfiles(custom) {
+		if profile.Name == name {
+			return profile, nil
+		}
+	}
+	return Profile{}, fmt.Errorf("unknown profile %v", name)
+}
+
+// Apply returns copy of the options with schedule and thresholds of the profile
+func (p Profile) Apply(options GoStressOptions) GoStressOptions {
+	if p.Transform != nil {
+		options.Schedule = p.Transform(options.Schedule)
+	}
+	if p.Thresholds != nil {
+		options.Thresholds = *p.Thresholds
+	}
+	options.Profile = p.Name
+	return options
+}
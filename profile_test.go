@@ -0,0 +1,95 @@
+package gostress
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProfiles(t *testing.T) {
+	base := GoStressOptions{
+		Schedule: LoadSchedule{
+			{Name: "warmup", Rps: 10, Workers: 1, Duration: time.Minute},
+			{Name: "peak", Rps: 100, Workers: 8, Duration: 5 * time.Minute},
+		},
+		Thresholds: ThresholdOptions{MaxErrorRate: 0.1},
+	}
+
+	smoke, err := ResolveProfile("smoke", nil)
+	require.Nil(t, err)
+	options := smoke.Apply(base)
+	assert.Equal(t, "smoke", options.Profile)
+	assert.Equal(t, LoadSchedule{{Name: "smoke", Rps: 1, Workers: 1, Duration: 10 * time.Second}}, options.Schedule)
+	assert.Equal(t, ThresholdOptions{FailOnErrors: true}, options.Thresholds)
+
+	load, err := ResolveProfile("load", nil)
+	require.Nil(t, err)
+	assert.Equal(t, base.Schedule, load.Apply(base).Schedule)
+	assert.Equal(t, base.Thresholds, load.Apply(base).Thresholds)
+
+	stress, err := ResolveProfile("stress", nil)
+	require.Nil(t, err)
+	assert.Equal(t, 200, stress.Apply(base).Schedule[1].Rps)
+	assert.Equal(t, 16, stress.Apply(base).Schedule[1].Workers)
+
+	spike, err := ResolveProfile("spike", nil)
+	require.Nil(t, err)
+	spiked := spike.Apply(base).Schedule
+	require.Len(t, spiked, 5)
+	assert.Equal(t, 100, spiked[0].Rps)
+	assert.Equal(t, 500, spiked[2].Rps)
+	assert.Equal(t, 100, spiked[4].Rps)
+
+	soak, err := ResolveProfile("soak", nil)
+	require.Nil(t, err)
+	assert.Equal(t, LoadSchedule{{Name: "soak", Rps: 100, Workers: 8, Duration: 4 * time.Hour}}, soak.Apply(base).Schedule)
+
+	_, err = ResolveProfile("unknown", nil)
+	assert.NotNil(t, err)
+
+	custom := Profile{Name: "smoke", Transform: func(LoadSchedule) LoadSchedule { return LoadSchedule{{Rps: 5, Workers: 1, Duration: time.Second}} }}
+	smoke, err = ResolveProfile("smoke", []Profile{custom})
+	require.Nil(t, err)
+	assert.Equal(t, 5, smoke.Apply(base).Schedule[0].Rps)
+	assert.Len(t, AvailableProfiles([]Profile{custom}), 5)
+}
+
+func TestProfileLabel(t *testing.T) {
+	stress, shutdown := NewStress(t.Name(), GoStressOptions{Profile: "smoke"}, zaptest.NewLogger(t).Sugar(), t, func(ctx RequestContext) error { return nil })
+	defer shutdown()
+	assert.Equal(t, 1, stress.Schedule[0].Rps)
+	families, err := stress.Metrics.Registry.Gather()
+	require.Nil(t, err)
+	for _, family := range families {
+		if !strings.HasPrefix(family.GetName(), "gostress_") {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			assert.Equal(t, "smoke", labels["gostress_profile"], family.GetName())
+		}
+	}
+}
+
+func TestInvalidProfile(t *testing.T) {
+	logger := zaptest.NewLogger(t).Sugar()
+	failures := &cliFailures{logger: logger}
+	calls := 0
+	stress, shutdown := NewStress(t.Name(), GoStressOptions{Profile: "load"}, logger, failures, func(ctx RequestContext) error {
+		calls++
+		return nil
+	})
+	defer shutdown()
+	assert.True(t, failures.Failed())
+	result := stress.RunLocal(context.Background())
+	assert.True(t, result.Aborted)
+	assert.Contains(t, result.AbortReason, "schedule is empty")
+	assert.Zero(t, calls)
+}
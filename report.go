@@ -2,7 +2,6 @@ package gostress
 
 import (
 	"fmt"
-	"github.com/prometheus/client_golang/prometheus"
 	io_prometheus_client "github.com/prometheus/client_model/go"
 	"go.uber.org/zap"
 	"strings"
@@ -30,7 +29,7 @@ func PrintMetric(name string, metrics []*io_prometheus_client.Metric) string {
 		var current m
 		labels := make([]string, 0)
 		for _, label := range metric.GetLabel() {
-			if label.GetName() == "gostress_name" || label.GetName() == "gostress_category" || label.GetValue() == "gostress" {
+			if label.GetName() == "gostress_name" || label.GetName() == "gostress_category" || label.GetName() == "gostress_profile" || label.GetValue() == "gostress" {
 				continue
 			}
 			labels = append(labels, fmt.Sprintf("%v:%v", label.GetName(), label.GetValue()))
@@ -65,45 +64,104 @@ func PrintMetric(name string, metrics []*io_prometheus_client.Metric) string {
 	}
 }
 
-func PrintStat() (string, error) {
-	metrics, err := prometheus.DefaultGatherer.Gather()
+func printLatencyBlock(title, label string, histograms map[string]*LatencyHistogram) string {
+	summaries, _ := summarizeHistograms(histograms)
+	return printSummaryBlock(title, label, summaries)
+}
+
+func printSummaryBlock(title, label string, summaries map[string]LatencySummary) string {
+	lines := make([]string, 0, len(summaries)+1)
+	lines = append(lines, fmt.Sprintf("%32v:", title))
+	for _, key := range sortedKeys(summaries) {
+		summary := summaries[key]
+		lines = append(lines, fmt.Sprintf(
+			"%32v: count=%v, avg=%v, p50=%v, p90=%v, p99=%v, p99.9=%v, p99.99=%v, max=%v",
+			fmt.Sprintf("{%v:%v}", label, key),
+			summary.Count,
+			roundLatency(summary.Avg),
+			roundLatency(summary.P50),
+			roundLatency(summary.P90),
+			roundLatency(summary.P99),
+			roundLatency(summary.P999),
+			roundLatency(summary.P9999),
+			roundLatency(summary.Max),
+		))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// PrintLatency prints latency block for every stage of the schedule followed by the total block for the whole run
+func PrintLatency(name string, recorder *LatencyRecorder) string {
+	return printRecorder(name, "status", recorder)
+}
+
+// PrintPhases prints breakdown of the request latency by the named phases for every stage and for the whole run
+func PrintPhases(name string, recorder *LatencyRecorder) string {
+	return printRecorder(name, "phase", recorder)
+}
+
+func printRecorder(name, label string, recorder *LatencyRecorder) string {
+	blocks := make([]string, 0)
+	for _, stage := range recorder.Stages() {
+		blocks = append(blocks, printLatencyBlock(stage.String(), label, recorder.StageSnapshot(stage.Index)))
+	}
+	blocks = append(blocks, printLatencyBlock("total", label, recorder.Snapshot()))
+	return fmt.Sprintf("%32v:\n%v", name, strings.Join(blocks, "\n"))
+}
+
+func PrintStat(metrics *Metrics) (string, error) {
+	families, err := metrics.Registry.Gather()
 	if err != nil {
 		return "", err
 	}
 	stat := strings.Builder{}
-	for _, metric := range metrics {
+	for _, metric := range families {
 		name := metric.GetName()
 		if strings.HasPrefix(name, "go_") || strings.HasPrefix(name, "process_") {
 			continue
 		}
+		if name == "gostress_request_latency" {
+			stat.WriteString(fmt.Sprintf("%v\n", PrintLatency(name, metrics.Latency)))
+			continue
+		}
+		if name == "gostress_phase_latency" {
+			stat.WriteString(fmt.Sprintf("%v\n", PrintPhases(name, metrics.Phases)))
+			continue
+		}
 		stat.WriteString(fmt.Sprintf("%v\n", PrintMetric(name, metric.GetMetric())))
 	}
 	return stat.String(), nil
 }
 
-func Monitor(name string, interval time.Duration, logger *zap.SugaredLogger) func() {
-	startTime := time.Now()
-	finish := make(chan struct{})
+// Monitor warns when load generator can't keep up with the schedule until returned function is called
+func Monitor(name string, metrics *Metrics, logger *zap.SugaredLogger) func() {
+	finish, finished := make(chan struct{}), make(chan struct{})
+	pacing := pacingWarning{tolerance: 0.1, checks: rateWindow}
 	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
 		for {
 			select {
-			case <-time.NewTimer(interval).C:
-				stat, err := PrintStat()
-				if err != nil {
-					logger.Errorf("unable to gather metrics: %v", err)
-				} else {
-					logger.Infof("stress test stat (%v, elapsed %v)\n%v", name, time.Since(startTime), stat)
+			case <-ticker.C:
+				expected, achieved := metricValue(metrics.ExpectedRpsGauge), metricValue(metrics.AchievedRpsGauge)
+				if pacing.Check(expected, achieved) {
+					metrics.Events.Emit(
+						EventBehindSchedule, fmt.Sprintf("achieved rps %.1f is below expected rps %.0f for %vs", achieved, expected, pacing.below),
+						"expected_rps", fmt.Sprintf("%.0f", expected), "achieved_rps", fmt.Sprintf("%.1f", achieved),
+					)
+					logger.Warnf(
+						"!!! LOAD GENERATOR IS BEHIND SCHEDULE: achieved rps %.1f is below expected rps %.0f for %vs (%v), results may be misleading !!!",
+						achieved, expected, pacing.below, name,
+					)
 				}
 			case <-finish:
-				stat, err := PrintStat()
-				if err != nil {
-					logger.Errorf("unable to gather metrics: %v", err)
-				} else {
-					logger.Infof("stress test stat (%v, final)\n%v", name, stat)
-				}
 				return
 			}
 		}
 	}()
-	return func() { finish <- struct{}{} }
+	return func() {
+		close(finish)
+		<-finished
+	}
 }
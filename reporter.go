@@ -0,0 +1,416 @@
+package gostress
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"go.uber.org/zap"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+type (
+	ReportOptions struct {
+		// Formats lists built-in reporters in the "format" or "format:path" form (text, json, markdown, junit)
+		// Reporters without path write to stdout (text reporter writes to the log); text reporter is used when Formats is empty
+		Formats []string
+		// Reporters are custom reporters invoked along with the built-in ones
+		Reporters []Reporter
+	}
+
+	// Reporter presents state of the stress run; it is invoked every report interval and once more on completion
+	Reporter interface {
+		Report(snapshot ReportSnapshot) error
+	}
+
+	// ReportSnapshot is a structured state of the stress run passed to the reporters
+	ReportSnapshot struct {
+		Name    string        `json:"name"`
+		Profile string        `json:"profile,omitempty"`
+		Time    time.Time     `json:"time"`
+		Elapsed time.Duration `json:"elapsed"`
+		// Final is set for the report on completion, Result is available only in this case
+		Final             bool          `json:"final"`
+		Stage             int           `json:"stage"`
+		StageName         string        `json:"stage_name,omitempty"`
+		ExpectedRps       float64       `json:"expected_rps"`
+		AchievedRps       float64       `json:"achieved_rps"`
+		CompletedRps      float64       `json:"completed_rps"`
+		Workers           int           `json:"workers"`
+		SaturationReasons []string      `json:"saturation_reasons,omitempty"`
+		Total             StageResult   `json:"total"`
+		Stages            []StageResult `json:"stages"`
+		Result            *Result       `json:"result,omitempty"`
+	}
+
+	// TextReporter writes human-readable report to the log
+	TextReporter struct {
+		Logger *zap.SugaredLogger
+		// FinalOnly skips interval reports (used when live dashboard is shown instead)
+		FinalOnly bool
+	}
+
+	// JsonReporter writes every snapshot as a single JSON line
+	JsonReporter struct{ Out io.Writer }
+
+	// MarkdownReporter writes summary tables suitable for PR comments on completion
+	MarkdownReporter struct{ Out io.Writer }
+
+	// JUnitReporter writes JUnit XML with a test case per stage on completion, so CI can show it in the test tab
+	JUnitReporter struct{ Out io.Writer }
+
+	fileReporter struct {
+		Reporter
+		file *os.File
+	}
+
+	junitTestSuites struct {
+		XMLName xml.Name         `xml:"testsuites"`
+		Suites  []junitTestSuite `xml:"testsuite"`
+	}
+	junitTestSuite struct {
+		Name       string          `xml:"name,attr"`
+		Tests      int             `xml:"tests,attr"`
+		Failures   int             `xml:"failures,attr"`
+		Time       float64         `xml:"time,attr"`
+		Timestamp  string          `xml:"timestamp,attr"`
+		Properties []junitProperty `xml:"properties>property,omitempty"`
+		Cases      []junitTestCase `xml:"testcase"`
+	}
+	junitProperty struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:"value,attr"`
+	}
+	junitTestCase struct {
+		Name      string        `xml:"name,attr"`
+		ClassName string        `xml:"classname,attr"`
+		Time      float64       `xml:"time,attr"`
+		Failure   *junitFailure `xml:"failure,omitempty"`
+		SystemOut string        `xml:"system-out,omitempty"`
+	}
+	junitFailure struct {
+		Message string `xml:"message,attr"`
+		Text    string `xml:",chardata"`
+	}
+)
+
+// NewReporter creates built-in reporter from the "format" or "format:path" description
+func NewReporter(description string, logger *zap.SugaredLogger) (Reporter, error) {
+	format, path, _ := strings.Cut(description, ":")
+	if format == "text" {
+		if path != "" {
+			return nil, fmt.Errorf("text reporter writes to the log and doesn't support path: %v", description)
+		}
+		return &TextReporter{Logger: logger}, nil
+	}
+	var out io.Writer = os.Stdout
+	var file *os.File
+	if path != "" {
+		var err error
+		if file, err = os.Create(path); err != nil {
+			return nil, fmt.Errorf("unable to create report file %v: %w", path, err)
+		}
+		out = file
+	}
+	var reporter Reporter
+	switch format {
+	case "json":
+		reporter = &JsonReporter{Out: out}
+	case "markdown":
+		reporter = &MarkdownReporter{Out: out}
+	case "junit":
+		reporter = &JUnitReporter{Out: out}
+	default:
+		if file != nil {
+			_ = file.Close()
+		}
+		return nil, fmt.Errorf("unknown report format %v (expected text, json, markdown or junit)", format)
+	}
+	if file != nil {
+		return &fileReporter{Reporter: reporter, file: file}, nil
+	}
+	return reporter, nil
+}
+
+func (r *fileReporter) Close() error { return r.file.Close() }
+
+// TakeReportSnapshot captures state of the metrics accumulated since the start snapshot
+func TakeReportSnapshot(name, profile string, start MetricsSnapshot, metrics *Metrics) ReportSnapshot {
+	current := metrics.Snapshot()
+	stage := metrics.Stage()
+	snapshot := ReportSnapshot{
+		Name:         name,
+		Profile:      profile,
+		Time:         current.Time,
+		Elapsed:      current.Time.Sub(start.Time),
+		Stage:        stage.Index,
+		StageName:    stage.Name,
+		ExpectedRps:  metricValue(metrics.ExpectedRpsGauge),
+		AchievedRps:  metricValue(metrics.AchievedRpsGauge),
+		CompletedRps: metricValue(metrics.CompletedRpsGauge),
+		Workers:      int(metricValue(metrics.CurrentWorkersGauge)),
+		Total:        current.StageResult(start),
+		Stages:       make([]StageResult, 0),
+	}
+	snapshot.Total.Histograms = nil
+	for _, stage := range metrics.Latency.Stages() {
+		result := StageResult{Index: stage.Index, Params: LoadParams{Name: stage.Name}}
+		result.Latency, _ = summarizeHistograms(metrics.Latency.StageSnapshot(stage.Index))
+		result.Phases, _ = summarizeHistograms(metrics.Phases.StageSnapshot(stage.Index))
+		snapshot.Stages = append(snapshot.Stages, result)
+	}
+	return snapshot
+}
+
+// WithResult turns snapshot into the final one with totals taken from the result of the run
+func (s ReportSnapshot) WithResult(result Result) ReportSnapshot {
+	s.Final = true
+	s.Profile = result.Profile
+	s.Result = &result
+	s.Total, s.Stages = result.Total, result.Stages
+	// live gauges average over the sliding window, final rates are computed over the whole run
+	s.AchievedRps, s.CompletedRps = result.Total.AchievedRps, 0
+	if result.Total.Duration > 0 {
+		completed := int64(0)
+		for _, latency := range result.Total.Latency {
+			completed += latency.Count
+		}
+		s.CompletedRps = float64(completed) / result.Total.Duration.Seconds()
+	}
+	s.SaturationReasons = result.SaturationReasons
+	return s
+}
+
+func (s ReportSnapshot) stageTitle(stage StageResult) string {
+	return Stage{Index: stage.Index, Name: stage.Params.Name}.String()
+}
+
+// PrintSnapshot prints snapshot in the same aligned layout as PrintStat
+func PrintSnapshot(snapshot ReportSnapshot) string {
+	lines := make([]string, 0)
+	if snapshot.Profile != "" {
+		lines = append(lines, fmt.Sprintf("%32v: %v", "profile", snapshot.Profile))
+	}
+	lines = append(lines,
+		fmt.Sprintf("%32v: %v", "stage", Stage{Index: snapshot.Stage, Name: snapshot.StageName}),
+		fmt.Sprintf("%32v: %.0f", "expected rps", snapshot.ExpectedRps),
+		fmt.Sprintf("%32v: %.1f", "achieved rps", snapshot.AchievedRps),
+		fmt.Sprintf("%32v: %.1f", "completed rps", snapshot.CompletedRps),
+		fmt.Sprintf("%32v: %v", "workers", snapshot.Workers),
+		fmt.Sprintf("%32v: %v", "sent", snapshot.Total.Sent),
+		fmt.Sprintf("%32v: %v", "skipped", snapshot.Total.Skipped),
+		fmt.Sprintf("%32v: %v", "errors", snapshot.Total.Errors),
+	)
+	for _, class := range sortedKeys(snapshot.Total.ErrorClasses) {
+		lines = append(lines, fmt.Sprintf("%32v: %v", fmt.Sprintf("{class:%v}", class), snapshot.Total.ErrorClasses[class]))
+	}
+	lines = append(lines, fmt.Sprintf("%32v:", "latency"))
+	for _, stage := range snapshot.Stages {
+		lines = append(lines, printSummaryBlock(snapshot.stageTitle(stage), "status", stage.Latency))
+	}
+	lines = append(lines, printSummaryBlock("total", "status", snapshot.Total.Latency))
+	if len(snapshot.Total.Phases) > 0 {
+		lines = append(lines, fmt.Sprintf("%32v:", "phases"))
+		for _, stage := range snapshot.Stages {
+			lines = append(lines, printSummaryBlock(snapshot.stageTitle(stage), "phase", stage.Phases))
+		}
+		lines = append(lines, printSummaryBlock("total", "phase", snapshot.Total.Phases))
+	}
+	if snapshot.Result != nil && len(NotableEvents(snapshot.Result.Events)) > 0 {
+		lines = append(lines, fmt.Sprintf("%32v:", "events"))
+		for _, event := range NotableEvents(snapshot.Result.Events) {
+			lines = append(lines, fmt.Sprintf("%32v: %v: %v", event.Time.Format("15:04:05.000"), event.Kind, event.Message))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (r *TextReporter) Report(snapshot ReportSnapshot) error {
+	if !snapshot.Final {
+		if r.FinalOnly {
+			return nil
+		}
+		if len(snapshot.SaturationReasons) > 0 {
+			r.Logger.Warnf(
+				"stress test stat (%v, elapsed %v) [GENERATOR SATURATED: %v]\n%v",
+				snapshot.Name, snapshot.Elapsed, strings.Join(snapshot.SaturationReasons, ", "), PrintSnapshot(snapshot),
+			)
+		} else {
+			r.Logger.Infof("stress test stat (%v, elapsed %v)\n%v", snapshot.Name, snapshot.Elapsed, PrintSnapshot(snapshot))
+		}
+		return nil
+	}
+	r.Logger.Infof("stress test stat (%v, final)\n%v", snapshot.Name, PrintSnapshot(snapshot))
+	return nil
+}
+
+func (r *JsonReporter) Report(snapshot ReportSnapshot) error {
+	if err := json.NewEncoder(r.Out).Encode(snapshot); err != nil {
+		return fmt.Errorf("unable to write json report: %w", err)
+	}
+	return nil
+}
+
+func (r *MarkdownReporter) Report(snapshot ReportSnapshot) error {
+	if !snapshot.Final {
+		return nil
+	}
+	report := strings.Builder{}
+	if snapshot.Profile != "" {
+		report.WriteString(fmt.Sprintf("### gostress: %v (profile %v)\n\n", snapshot.Name, snapshot.Profile))
+	} else {
+		report.WriteString(fmt.Sprintf("### gostress: %v\n\n", snapshot.Name))
+	}
+	if reason := snapshot.Result.failure(); reason != "" {
+		report.WriteString(fmt.Sprintf("> **%v**\n\n", reason))
+	}
+	report.WriteString("| stage | rps | workers | duration | sent | errors | skipped | achieved rps | status | count | p50 | p90 | p99 | max |\n")
+	report.WriteString("|---|---:|---:|---:|---:|---:|---:|---:|---|---:|---:|---:|---:|---:|\n")
+	row := func(title string, stage StageResult, rps, workers string) {
+		statuses := sortedKeys(stage.Latency)
+		if len(statuses) == 0 {
+			statuses = []string{""}
+		}
+		for _, status := range statuses {
+			latency := stage.Latency[status]
+			report.WriteString(fmt.Sprintf(
+				"| %v | %v | %v | %v | %v | %v | %v | %.1f | %v | %v | %v | %v | %v | %v |\n",
+				title, rps, workers, stage.Duration.Round(time.Millisecond), stage.Sent, stage.Errors, stage.Skipped, stage.AchievedRps,
+				status, latency.Count, roundLatency(latency.P50), roundLatency(latency.P90), roundLatency(latency.P99), roundLatency(latency.Max),
+			))
+		}
+	}
+	for _, stage := range snapshot.Stages {
+		row(snapshot.stageTitle(stage), stage, fmt.Sprint(stage.Params.Rps), fmt.Sprint(stage.Params.Workers))
+	}
+	row("total", snapshot.Total, "", "")
+	if len(snapshot.Total.Phases) > 0 {
+		report.WriteString("\n| phase | count | avg | p50 | p90 | p99 | max |\n")
+		report.WriteString("|---|---:|---:|---:|---:|---:|---:|\n")
+		for _, phase := range sortedKeys(snapshot.Total.Phases) {
+			latency := snapshot.Total.Phases[phase]
+			report.WriteString(fmt.Sprintf(
+				"| %v | %v | %v | %v | %v | %v | %v |\n",
+				phase, latency.Count, roundLatency(latency.Avg), roundLatency(latency.P50), roundLatency(latency.P90), roundLatency(latency.P99), roundLatency(latency.Max),
+			))
+		}
+	}
+	if events := NotableEvents(snapshot.Result.Events); len(events) > 0 {
+		report.WriteString("\n| time | event | message |\n")
+		report.WriteString("|---|---|---|\n")
+		for _, event := range events {
+			report.WriteString(fmt.Sprintf("| %v | %v | %v |\n", event.Time.Format("15:04:05.000"), event.Kind, strings.ReplaceAll(event.Message, "|", "\\|")))
+		}
+	}
+	if _, err := io.WriteString(r.Out, report.String()); err != nil {
+		return fmt.Errorf("unable to write markdown report: %w", err)
+	}
+	return nil
+}
+
+func (r *JUnitReporter) Report(snapshot ReportSnapshot) error {
+	if !snapshot.Final {
+		return nil
+	}
+	suite := junitTestSuite{
+		Name:      snapshot.Name,
+		Time:      snapshot.Result.Duration.Seconds(),
+		Timestamp: snapshot.Result.StartTime.UTC().Format("2006-01-02T15:04:05"),
+	}
+	if snapshot.Profile != "" {
+		suite.Properties = append(suite.Properties, junitProperty{Name: "profile", Value: snapshot.Profile})
+	}
+	for _, stage := range snapshot.Stages {
+		suite.Cases = append(suite.Cases, junitTestCase{
+			Name:      snapshot.stageTitle(stage),
+			ClassName: snapshot.Name,
+			Time:      stage.Duration.Seconds(),
+			SystemOut: printSummaryBlock(snapshot.stageTitle(stage), "status", stage.Latency),
+		})
+	}
+	total := junitTestCase{
+		Name:      "total",
+		ClassName: snapshot.Name,
+		Time:      snapshot.Result.Duration.Seconds(),
+		SystemOut: PrintSnapshot(snapshot),
+	}
+	if reason := snapshot.Result.failure(); reason != "" {
+		total.Failure = &junitFailure{Message: reason, Text: reason}
+		suite.Failures++
+	}
+	suite.Cases = append(suite.Cases, total)
+	suite.Tests = len(suite.Cases)
+	content, err := xml.MarshalIndent(junitTestSuites{Suites: []junitTestSuite{suite}}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to serialize junit report: %w", err)
+	}
+	if _, err := fmt.Fprintf(r.Out, "%v%s\n", xml.Header, content); err != nil {
+		return fmt.Errorf("unable to write junit report: %w", err)
+	}
+	return nil
+}
+
+// failure describes why the run should be considered failed or returns empty string
+func (r *Result) failure() string {
+	switch {
+	case r.Aborted:
+		return fmt.Sprintf("aborted: %v", r.AbortReason)
+	case r.Invalid:
+		return fmt.Sprintf("invalid: %v", r.InvalidReason)
+	case r.TeardownError != "":
+		return r.TeardownError
+	}
+	return ""
+}
+
+// ReportResult invokes reporters with the final snapshot and closes the files opened by NewReporter
+func ReportResult(snapshot ReportSnapshot, reporters []Reporter, logger *zap.SugaredLogger) {
+	for _, reporter := range reporters {
+		if err := reporter.Report(snapshot); err != nil {
+			logger.Errorf("unable to report stress stat: %v", err)
+		}
+		// reporters passed with Options.Report.Reporters are owned by the caller and are not closed
+		if file, ok := reporter.(*fileReporter); ok {
+			if err := file.Close(); err != nil {
+				logger.Errorf("unable to close reporter: %v", err)
+			}
+		}
+	}
+}
+
+// RunReporters invokes reporters every interval and once more with the result of the run when returned function is called
+func RunReporters(name, profile string, interval time.Duration, metrics *Metrics, reporters []Reporter, logger *zap.SugaredLogger) func(result Result) {
+	start := metrics.Snapshot()
+	lastReport := start.Time
+	report := func(snapshot ReportSnapshot) {
+		for _, reporter := range reporters {
+			if err := reporter.Report(snapshot); err != nil {
+				logger.Errorf("unable to report stress stat: %v", err)
+			}
+		}
+	}
+	finish, finished := make(chan Result), make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				snapshot := TakeReportSnapshot(name, profile, start, metrics)
+				snapshot.SaturationReasons = SaturationReasons(metrics.Saturation.Samples(lastReport, now))
+				lastReport = now
+				report(snapshot)
+			case result := <-finish:
+				ReportResult(TakeReportSnapshot(name, profile, start, metrics).WithResult(result), reporters, logger)
+				return
+			}
+		}
+	}()
+	return func(result Result) {
+		finish <- result
+		<-finished
+	}
+}
@@ -0,0 +1,132 @@
+package gostress
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type collectingReporter struct{ snapshots []ReportSnapshot }
+
+func (r *collectingReporter) Report(snapshot ReportSnapshot) error {
+	r.snapshots = append(r.snapshots, snapshot)
+	return nil
+}
+
+// closingReporter is owned by the test, so ReportResult must not close it
+type closingReporter struct {
+	collectingReporter
+	closed bool
+}
+
+func (r *closingReporter) Close() error {
+	r.closed = true
+	return nil
+}
+
+func reporterMetrics(t *testing.T) *Metrics {
+	metrics := NewMetrics(t.Name(), nil)
+	metrics.SetStage(Stage{Index: 0, Name: "warmup"})
+	for i := 0; i < 10; i++ {
+		metrics.ObserveTrigger(0, true)
+		metrics.ObserveLatency(metrics.Stage(), "success", time.Duration(i+1)*time.Millisecond)
+	}
+	metrics.ObservePhase(metrics.Stage(), "auth", time.Millisecond)
+	return metrics
+}
+
+func reporterResult(snapshot ReportSnapshot) Result {
+	stage := snapshot.Total
+	stage.Params = LoadParams{Name: "warmup", Rps: 10, Workers: 1, Duration: time.Second}
+	return Result{Name: snapshot.Name, StartTime: time.Now(), Duration: time.Second, Total: snapshot.Total, Stages: []StageResult{stage}}
+}
+
+func TestReporters(t *testing.T) {
+	metrics := reporterMetrics(t)
+	collecting := &collectingReporter{}
+	finish := RunReporters(t.Name(), "", 50*time.Millisecond, metrics, []Reporter{collecting}, zaptest.NewLogger(t).Sugar())
+	time.Sleep(120 * time.Millisecond)
+	finish(Result{Name: t.Name(), Aborted: true, AbortReason: "canceled"})
+
+	require.GreaterOrEqual(t, len(collecting.snapshots), 3)
+	last := collecting.snapshots[len(collecting.snapshots)-1]
+	assert.True(t, last.Final)
+	require.NotNil(t, last.Result)
+	assert.True(t, last.Result.Aborted)
+	for _, snapshot := range collecting.snapshots[:len(collecting.snapshots)-1] {
+		assert.False(t, snapshot.Final)
+		assert.Equal(t, "warmup", snapshot.StageName)
+		require.Len(t, snapshot.Stages, 1)
+		assert.Equal(t, int64(10), snapshot.Stages[0].Latency["success"].Count)
+		assert.Equal(t, int64(1), snapshot.Stages[0].Phases["auth"].Count)
+	}
+}
+
+func TestReportFormats(t *testing.T) {
+	metrics := reporterMetrics(t)
+	start := MetricsSnapshot{Latency: map[string]*LatencyHistogram{}, Phases: map[string]*LatencyHistogram{}}
+	snapshot := TakeReportSnapshot(t.Name(), "smoke", start, metrics)
+	assert.Equal(t, int64(10), snapshot.Total.Sent)
+	assert.Contains(t, PrintSnapshot(snapshot), "{phase:auth}")
+
+	output := &bytes.Buffer{}
+	require.Nil(t, (&JsonReporter{Out: output}).Report(snapshot))
+	var decoded ReportSnapshot
+	require.Nil(t, json.Unmarshal(output.Bytes(), &decoded))
+	assert.Equal(t, snapshot.Total.Latency, decoded.Total.Latency)
+
+	final := snapshot.WithResult(reporterResult(snapshot))
+	assert.Equal(t, snapshot.Total.AchievedRps, final.AchievedRps)
+
+	total := StageResult{Duration: 2 * time.Second, AchievedRps: 8.5, Latency: map[string]LatencySummary{"success": {Count: 15}, "error": {Count: 2}}}
+	rates := ReportSnapshot{AchievedRps: 3, CompletedRps: 7}.WithResult(Result{Total: total})
+	assert.Equal(t, 8.5, rates.AchievedRps)
+	assert.Equal(t, 8.5, rates.CompletedRps)
+	output.Reset()
+	require.Nil(t, (&MarkdownReporter{Out: output}).Report(snapshot))
+	assert.Empty(t, output.String())
+	require.Nil(t, (&MarkdownReporter{Out: output}).Report(final))
+	assert.Contains(t, output.String(), "| stage 0 (warmup) | 10 | 1 |")
+	assert.Contains(t, output.String(), "| auth | 1 |")
+
+	result := reporterResult(snapshot)
+	result.Invalid, result.InvalidReason = true, "saturated"
+	output.Reset()
+	require.Nil(t, (&JUnitReporter{Out: output}).Report(snapshot.WithResult(result)))
+	var suites junitTestSuites
+	require.Nil(t, xml.Unmarshal(output.Bytes(), &suites))
+	require.Len(t, suites.Suites, 1)
+	assert.Equal(t, 2, suites.Suites[0].Tests)
+	assert.Equal(t, 1, suites.Suites[0].Failures)
+	assert.Equal(t, "invalid: saturated", suites.Suites[0].Cases[1].Failure.Message)
+}
+
+func TestNewReporter(t *testing.T) {
+	logger := zaptest.NewLogger(t).Sugar()
+	_, err := NewReporter("yaml", logger)
+	assert.NotNil(t, err)
+	_, err = NewReporter("text:report.txt", logger)
+	assert.NotNil(t, err)
+
+	path := filepath.Join(t.TempDir(), "report.md")
+	reporter, err := NewReporter("markdown:"+path, logger)
+	require.Nil(t, err)
+	metrics := reporterMetrics(t)
+	owned := &closingReporter{}
+	finish := RunReporters(t.Name(), "", time.Minute, metrics, []Reporter{reporter, owned}, logger)
+	finish(Result{Name: t.Name()})
+	content, err := os.ReadFile(path)
+	require.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(content), "### gostress: TestNewReporter"))
+	assert.NotNil(t, reporter.(*fileReporter).file.Close())
+	assert.Len(t, owned.snapshots, 1)
+	assert.False(t, owned.closed)
+}
@@ -0,0 +1,169 @@
+package gostress
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+type (
+	// Result is a structured outcome of the stress run which can be asserted in tests or serialized to JSON
+	Result struct {
+		Name        string        `json:"name"`
+		Profile     string        `json:"profile,omitempty"`
+		StartTime   time.Time     `json:"start_time"`
+		EndTime     time.Time     `json:"end_time"`
+		Duration    time.Duration `json:"duration"`
+		Aborted     bool          `json:"aborted"`
+		AbortReason string        `json:"abort_reason,omitempty"`
+		// SetupError and TeardownError are errors of the lifecycle hooks
+		SetupError    string `json:"setup_error,omitempty"`
+		TeardownError string `json:"teardown_error,omitempty"`
+		// Invalid is set when load generator was saturated for too long, so measured latency can't be trusted
+		Invalid           bool          `json:"invalid"`
+		InvalidReason     string        `json:"invalid_reason,omitempty"`
+		SaturatedFraction float64       `json:"saturated_fraction"`
+		SaturationReasons []string      `json:"saturation_reasons,omitempty"`
+		Total             StageResult   `json:"total"`
+		Stages            []StageResult `json:"stages"`
+		// Events are notable moments of the run: stage changes, pool resizes, threshold breaches, aborts, etc
+		Events []Event `json:"events,omitempty"`
+	}
+	StageResult struct {
+		Index        int                       `json:"index"`
+		Params       LoadParams                `json:"params"`
+		StartTime    time.Time                 `json:"start_time"`
+		EndTime      time.Time                 `json:"end_time"`
+		Duration     time.Duration             `json:"duration"`
+		Sent         int64                     `json:"sent"`
+		Skipped      int64                     `json:"skipped"`
+		Errors       int64                     `json:"errors"`
+		AchievedRps  float64                   `json:"achieved_rps"`
+		Latency      map[string]LatencySummary `json:"latency"`
+		ErrorClasses map[string]int64          `json:"error_classes,omitempty"`
+		// Phases breaks request latency down by the named phases measured with RequestContext.StartPhase
+		Phases map[string]LatencySummary `json:"phases,omitempty"`
+		// Histograms keeps full latency distribution for every status, so results can be compared later
+		Histograms map[string]*LatencyHistogram `json:"histograms,omitempty"`
+	}
+	LatencySummary struct {
+		Count int64         `json:"count"`
+		Avg   time.Duration `json:"avg"`
+		P50   time.Duration `json:"p50"`
+		P90   time.Duration `json:"p90"`
+		P99   time.Duration `json:"p99"`
+		P999  time.Duration `json:"p999"`
+		P9999 time.Duration `json:"p9999"`
+		Max   time.Duration `json:"max"`
+	}
+
+	// MetricsSnapshot is a point-in-time copy of cumulative stress metrics
+	MetricsSnapshot struct {
+		Time         time.Time
+		Sent         int64
+		Skipped      int64
+		Errors       int64
+		Latency      map[string]*LatencyHistogram
+		Phases       map[string]*LatencyHistogram
+		ErrorClasses map[string]int64
+	}
+)
+
+func SummarizeLatency(h *LatencyHistogram) LatencySummary {
+	return LatencySummary{
+		Count: h.Count,
+		Avg:   h.Mean(),
+		P50:   h.Quantile(0.50),
+		P90:   h.Quantile(0.90),
+		P99:   h.Quantile(0.99),
+		P999:  h.Quantile(0.999),
+		P9999: h.Quantile(0.9999),
+		Max:   h.Max,
+	}
+}
+
+func summarizeHistograms(histograms map[string]*LatencyHistogram) (map[string]LatencySummary, map[string]*LatencyHistogram) {
+	summaries := make(map[string]LatencySummary, len(histograms))
+	for status, histogram := range histograms {
+		summaries[status] = SummarizeLatency(histogram)
+	}
+	return summaries, histograms
+}
+
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	return MetricsSnapshot{
+		Time:         time.Now(),
+		Sent:         int64(metricValue(m.SentRequestCounter)),
+		Skipped:      int64(metricValue(m.SkippedRequestCounter)),
+		Errors:       int64(metricValue(m.ErrorsCounter)),
+		Latency:      m.Latency.Snapshot(),
+		Phases:       m.Phases.Snapshot(),
+		ErrorClasses: m.ErrorClasses.Snapshot(),
+	}
+}
+
+// StageResult builds result for the period between the start and the current snapshot
+func (s MetricsSnapshot) StageResult(start MetricsSnapshot) StageResult {
+	result := StageResult{
+		StartTime:    start.Time,
+		EndTime:      s.Time,
+		Duration:     s.Time.Sub(start.Time),
+		Sent:         s.Sent - start.Sent,
+		Skipped:      s.Skipped - start.Skipped,
+		Errors:       s.Errors - start.Errors,
+		Latency:      make(map[string]LatencySummary),
+		ErrorClasses: make(map[string]int64),
+		Phases:       make(map[string]LatencySummary),
+		Histograms:   make(map[string]*LatencyHistogram),
+	}
+	if result.Duration > 0 {
+		result.AchievedRps = float64(result.Sent) / result.Duration.Seconds()
+	}
+	for status, histogram := range s.Latency {
+		if prev, ok := start.Latency[status]; ok {
+			histogram = histogram.Sub(prev)
+		}
+		if histogram.Count > 0 {
+			result.Latency[status] = SummarizeLatency(histogram)
+			result.Histograms[status] = histogram
+		}
+	}
+	for phase, histogram := range s.Phases {
+		if prev, ok := start.Phases[phase]; ok {
+			histogram = histogram.Sub(prev)
+		}
+		if histogram.Count > 0 {
+			result.Phases[phase] = SummarizeLatency(histogram)
+		}
+	}
+	for class, count := range s.ErrorClasses {
+		if count > start.ErrorClasses[class] {
+			result.ErrorClasses[class] = count - start.ErrorClasses[class]
+		}
+	}
+	return result
+}
+
+func (r Result) WriteFile(path string) error {
+	content, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to serialize result: %w", err)
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("unable to write result to %v: %w", path, err)
+	}
+	return nil
+}
+
+func ReadResult(path string) (Result, error) {
+	var result Result
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return result, fmt.Errorf("unable to read result from %v: %w", path, err)
+	}
+	if err := json.Unmarshal(content, &result); err != nil {
+		return result, fmt.Errorf("unable to parse result from %v: %w", path, err)
+	}
+	return result, nil
+}
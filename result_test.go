@@ -0,0 +1,37 @@
+package gostress
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"testing"
+	"time"
+)
+
+func TestStageResults(t *testing.T) {
+	metrics := NewMetrics(t.Name(), nil)
+	logger := zaptest.NewLogger(t).Sugar()
+	r := NewRunner(metrics)
+	pool := NewWorkerPool(time.Second, metrics, logger, func(ctx RequestContext) error {
+		if ctx.Id%10 == 0 {
+			return fmt.Errorf("request %v: %w", ctx.Id, context.DeadlineExceeded)
+		}
+		return nil
+	})
+	schedule := LoadSchedule{
+		{Name: "warmup", Rps: 100, Workers: 4, Duration: time.Second},
+		{Name: "peak", Rps: 100, Workers: 4, Duration: time.Second},
+	}
+	stages, err := r.RunSchedule(context.Background(), schedule, pool, logger)
+	require.Nil(t, err)
+	require.Len(t, stages, 2)
+	for i, stage := range stages {
+		assert.Equal(t, i, stage.Index)
+		assert.InDelta(t, 100, stage.Sent, 20)
+		assert.InDelta(t, 100, stage.AchievedRps, 20)
+		assert.Equal(t, stage.Errors, stage.ErrorClasses["timeout"])
+	}
+	assert.Equal(t, []Stage{{Index: 0, Name: "warmup"}, {Index: 1, Name: "peak"}}, metrics.Latency.Stages())
+}
@@ -0,0 +1,281 @@
+package gostress
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type (
+	ResultsRow struct {
+		Time            time.Time `json:"time"`
+		Elapsed         float64   `json:"elapsed"`
+		Stage           int       `json:"stage"`
+		StageName       string    `json:"stage_name,omitempty"`
+		Interval        float64   `json:"interval"`
+		ExpectedRps     float64   `json:"expected_rps"`
+		AchievedRps     float64   `json:"achieved_rps"`
+		ExpectedWorkers float64   `json:"expected_workers"`
+		CurrentWorkers  float64   `json:"current_workers"`
+		Sent            int64     `json:"sent"`
+		Errors          int64     `json:"errors"`
+		Skipped         int64     `json:"skipped"`
+		// Saturated marks intervals during which load generator itself was saturated
+		Saturated         bool                `json:"saturated,omitempty"`
+		SaturationReasons []string            `json:"saturation_reasons,omitempty"`
+		Latency           []ResultsLatencyRow `json:"latency,omitempty"`
+	}
+	ResultsLatencyRow struct {
+		Status string  `json:"status"`
+		Count  int64   `json:"count"`
+		Avg    float64 `json:"avg"`
+		P50    float64 `json:"p50"`
+		P90    float64 `json:"p90"`
+		P99    float64 `json:"p99"`
+		P999   float64 `json:"p999"`
+		Max    float64 `json:"max"`
+	}
+
+	ResultsWriter interface {
+		Write(row ResultsRow) error
+		Close() error
+	}
+	jsonlResultsWriter struct{ file *os.File }
+	csvResultsWriter   struct {
+		file   *os.File
+		writer *csv.Writer
+	}
+	// ResultsBuffer keeps all rows in memory in order to build reports after the run
+	ResultsBuffer struct {
+		lock sync.Mutex
+		rows []ResultsRow
+	}
+	multiResultsWriter []ResultsWriter
+
+	// ResultsRecorder computes per-interval ResultsRow from the cumulative stress metrics
+	ResultsRecorder struct {
+		metrics     *Metrics
+		startTime   time.Time
+		lastTime    time.Time
+		lastSent    float64
+		lastErrors  float64
+		lastSkipped float64
+		lastLatency map[string]*LatencyHistogram
+	}
+)
+
+var csvResultsHeader = []string{
+	"time", "elapsed", "stage", "stage_name", "interval", "expected_rps", "achieved_rps", "expected_workers", "current_workers",
+	"sent", "errors", "skipped", "saturated", "status", "count", "avg", "p50", "p90", "p99", "p999", "max",
+}
+
+// NewResultsWriter creates results file at the given path: CSV format is used for files with .csv extension and JSONL otherwise
+func NewResultsWriter(path string) (ResultsWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create results file %v: %w", path, err)
+	}
+	if filepath.Ext(path) != ".csv" {
+		return &jsonlResultsWriter{file: file}, nil
+	}
+	w := &csvResultsWriter{file: file, writer: csv.NewWriter(file)}
+	if err := w.flush(csvResultsHeader); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("unable to write csv header to %v: %w", path, err)
+	}
+	return w, nil
+}
+
+func (w *jsonlResultsWriter) Write(row ResultsRow) error {
+	line, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	_, err = w.file.Write(append(line, '\n'))
+	return err
+}
+
+func (w *jsonlResultsWriter) Close() error { return w.file.Close() }
+
+func (w *csvResultsWriter) Write(row ResultsRow) error {
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	common := []string{
+		row.Time.Format(time.RFC3339Nano), f(row.Elapsed), strconv.Itoa(row.Stage), row.StageName, f(row.Interval), f(row.ExpectedRps), f(row.AchievedRps),
+		f(row.ExpectedWorkers), f(row.CurrentWorkers),
+		strconv.FormatInt(row.Sent, 10), strconv.FormatInt(row.Errors, 10), strconv.FormatInt(row.Skipped, 10),
+		strconv.FormatBool(row.Saturated),
+	}
+	latency := row.Latency
+	if len(latency) == 0 {
+		latency = []ResultsLatencyRow{{}}
+	}
+	for _, l := range latency {
+		record := append(append([]string{}, common...),
+			l.Status, strconv.FormatInt(l.Count, 10), f(l.Avg), f(l.P50), f(l.P90), f(l.P99), f(l.P999), f(l.Max),
+		)
+		if err := w.flush(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *csvResultsWriter) flush(record []string) error {
+	if err := w.writer.Write(record); err != nil {
+		return err
+	}
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+func (w *csvResultsWriter) Close() error { return w.file.Close() }
+
+func (b *ResultsBuffer) Write(row ResultsRow) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.rows = append(b.rows, row)
+	return nil
+}
+
+func (b *ResultsBuffer) Close() error { return nil }
+
+func (b *ResultsBuffer) Rows() []ResultsRow {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return append([]ResultsRow(nil), b.rows...)
+}
+
+func (w multiResultsWriter) Write(row ResultsRow) error {
+	for _, writer := range w {
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w multiResultsWriter) Close() error {
+	var result error
+	for _, writer := range w {
+		if err := writer.Close(); err != nil && result == nil {
+			result = err
+		}
+	}
+	return result
+}
+
+// ReadResults loads rows from the JSONL results file
+func ReadResults(path string) ([]ResultsRow, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open results file %v: %w", path, err)
+	}
+	defer file.Close()
+	rows := make([]ResultsRow, 0)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var row ResultsRow
+		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+			// last line can be partially written if the run crashed
+			break
+		}
+		rows = append(rows, row)
+	}
+	return rows, scanner.Err()
+}
+
+func NewResultsRecorder(metrics *Metrics) *ResultsRecorder {
+	now := time.Now()
+	return &ResultsRecorder{metrics: metrics, startTime: now, lastTime: now, lastLatency: make(map[string]*LatencyHistogram)}
+}
+
+func (r *ResultsRecorder) Sample(now time.Time) ResultsRow {
+	sent := metricValue(r.metrics.SentRequestCounter)
+	errors := metricValue(r.metrics.ErrorsCounter)
+	skipped := metricValue(r.metrics.SkippedRequestCounter)
+	interval := now.Sub(r.lastTime).Seconds()
+	stage := r.metrics.Stage()
+	row := ResultsRow{
+		Time:            now,
+		Elapsed:         now.Sub(r.startTime).Seconds(),
+		Stage:           stage.Index,
+		StageName:       stage.Name,
+		Interval:        interval,
+		ExpectedRps:     metricValue(r.metrics.ExpectedRpsGauge),
+		ExpectedWorkers: metricValue(r.metrics.ExpectedWorkersGauge),
+		CurrentWorkers:  metricValue(r.metrics.CurrentWorkersGauge),
+		Sent:            int64(sent - r.lastSent),
+		Errors:          int64(errors - r.lastErrors),
+		Skipped:         int64(skipped - r.lastSkipped),
+	}
+	if interval > 0 {
+		row.AchievedRps = float64(row.Sent) / interval
+	}
+	if reasons := SaturationReasons(r.metrics.Saturation.Samples(r.lastTime, now)); len(reasons) > 0 {
+		row.Saturated, row.SaturationReasons = true, reasons
+	}
+	latency := r.metrics.Latency.Snapshot()
+	for _, status := range sortedKeys(latency) {
+		current := latency[status]
+		if prev, ok := r.lastLatency[status]; ok {
+			current = current.Sub(prev)
+		}
+		if current.Count == 0 {
+			continue
+		}
+		row.Latency = append(row.Latency, ResultsLatencyRow{
+			Status: status,
+			Count:  current.Count,
+			Avg:    current.Mean().Seconds(),
+			P50:    current.Quantile(0.50).Seconds(),
+			P90:    current.Quantile(0.90).Seconds(),
+			P99:    current.Quantile(0.99).Seconds(),
+			P999:   current.Quantile(0.999).Seconds(),
+			Max:    current.Max.Seconds(),
+		})
+	}
+	r.lastTime, r.lastSent, r.lastErrors, r.lastSkipped, r.lastLatency = now, sent, errors, skipped, latency
+	return row
+}
+
+// RecordResults writes ResultsRow to all writers every interval and once more on shutdown
+// Files created by NewResultsWriter flush every row immediately, so crashed run still leaves all rows written before the crash
+func RecordResults(interval time.Duration, metrics *Metrics, logger *zap.SugaredLogger, writers ...ResultsWriter) func() {
+	writer := multiResultsWriter(writers)
+	recorder := NewResultsRecorder(metrics)
+	finish, finished := make(chan struct{}), make(chan struct{})
+	write := func() {
+		if err := writer.Write(recorder.Sample(time.Now())); err != nil {
+			logger.Errorf("unable to write results row: %v", err)
+		}
+	}
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				write()
+			case <-finish:
+				write()
+				if err := writer.Close(); err != nil {
+					logger.Errorf("unable to close results writer: %v", err)
+				}
+				return
+			}
+		}
+	}()
+	return func() {
+		close(finish)
+		<-finished
+	}
+}
@@ -0,0 +1,37 @@
+package gostress
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestResultsRecorder(t *testing.T) {
+	metrics := NewMetrics(t.Name(), nil)
+	path := filepath.Join(t.TempDir(), "results.jsonl")
+	writer, err := NewResultsWriter(path)
+	require.Nil(t, err)
+
+	recorder := NewResultsRecorder(metrics)
+	now := time.Now()
+	metrics.SentRequestCounter.Add(10)
+	metrics.Latency.Record(Stage{}, "success", 10*time.Millisecond)
+	require.Nil(t, writer.Write(recorder.Sample(now.Add(time.Second))))
+	metrics.SentRequestCounter.Add(30)
+	metrics.ErrorsCounter.Add(2)
+	metrics.Latency.Record(Stage{}, "error", 20*time.Millisecond)
+	require.Nil(t, writer.Write(recorder.Sample(now.Add(2*time.Second))))
+	require.Nil(t, writer.Close())
+
+	rows, err := ReadResults(path)
+	require.Nil(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, int64(10), rows[0].Sent)
+	assert.Equal(t, int64(30), rows[1].Sent)
+	assert.Equal(t, int64(2), rows[1].Errors)
+	assert.InDelta(t, 30, rows[1].AchievedRps, 0.01)
+	require.Len(t, rows[1].Latency, 1)
+	assert.Equal(t, "error", rows[1].Latency[0].Status)
+}
@@ -0,0 +1,225 @@
+package gostress
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// SaturationOptions defines thresholds after which load generator itself is considered saturated,
+	// so latency measured during such intervals reflects the generator rather than the target
+	SaturationOptions struct {
+		// MaxSchedulingLag is a maximum p99 lag between intended and actual trigger time (default 50ms)
+		MaxSchedulingLag time.Duration
+		// MaxCpuUsage is a maximum fraction of available cpu (min of GOMAXPROCS and cgroup limit) used by the process (default 0.9)
+		MaxCpuUsage float64
+		// MaxGcPauseFraction is a maximum fraction of wall time spent in GC pauses (default 0.05)
+		MaxGcPauseFraction float64
+		// MaxGoroutines is a maximum number of goroutines (disabled by default)
+		MaxGoroutines int
+		// FailFraction marks the whole run as invalid if generator was saturated for more than this fraction of time (disabled by default)
+		FailFraction float64
+	}
+
+	SaturationSample struct {
+		Time             time.Time     `json:"time"`
+		SchedulingLagP99 time.Duration `json:"scheduling_lag_p99"`
+		Goroutines       int           `json:"goroutines"`
+		GcPauseFraction  float64       `json:"gc_pause_fraction"`
+		CpuUsage         float64       `json:"cpu_usage"`
+		CpuLimit         float64       `json:"cpu_limit"`
+		Reasons          []string      `json:"reasons,omitempty"`
+	}
+
+	// SaturationTracker samples health of the load generator process every second
+	SaturationTracker struct {
+		lock    sync.Mutex
+		options SaturationOptions
+		lag     *LatencyHistogram
+		samples []SaturationSample
+	}
+)
+
+func (o SaturationOptions) withDefaults() SaturationOptions {
+	if o.MaxSchedulingLag == 0 {
+		o.MaxSchedulingLag = 50 * time.Millisecond
+	}
+	if o.MaxCpuUsage == 0 {
+		o.MaxCpuUsage = 0.9
+	}
+	if o.MaxGcPauseFraction == 0 {
+		o.MaxGcPauseFraction = 0.05
+	}
+	return o
+}
+
+func NewSaturationTracker() *SaturationTracker {
+	return &SaturationTracker{options: SaturationOptions{}.withDefaults(), lag: NewLatencyHistogram()}
+}
+
+func (s SaturationSample) Saturated() bool { return len(s.Reasons) > 0 }
+
+// cgroupRoot is a mount point of the cgroup filesystem
+const cgroupRoot = "/sys/fs/cgroup"
+
+// cgroupCpuLimit returns cpu limit of the container (in cores) from cgroup v2 or v1 files under the root
+func cgroupCpuLimit(root string) (float64, bool) {
+	if content, err := os.ReadFile(filepath.Join(root, "cpu.max")); err == nil {
+		tokens := strings.Fields(string(content))
+		if len(tokens) == 2 && tokens[0] != "max" {
+			quota, quotaErr := strconv.ParseFloat(tokens[0], 64)
+			period, periodErr := strconv.ParseFloat(tokens[1], 64)
+			if quotaErr == nil && periodErr == nil && period > 0 {
+				return quota / period, true
+			}
+		}
+		return 0, false
+	}
+	quotaContent, quotaErr := os.ReadFile(filepath.Join(root, "cpu", "cpu.cfs_quota_us"))
+	periodContent, periodErr := os.ReadFile(filepath.Join(root, "cpu", "cpu.cfs_period_us"))
+	if quotaErr != nil || periodErr != nil {
+		return 0, false
+	}
+	quota, quotaErr := strconv.ParseFloat(strings.TrimSpace(string(quotaContent)), 64)
+	period, periodErr := strconv.ParseFloat(strings.TrimSpace(string(periodContent)), 64)
+	if quotaErr != nil || periodErr != nil || quota <= 0 || period <= 0 {
+		return 0, false
+	}
+	return quota / period, true
+}
+
+func availableCpu() float64 {
+	limit := float64(runtime.GOMAXPROCS(0))
+	if cgroup, ok := cgroupCpuLimit(cgroupRoot); ok && cgroup < limit {
+		limit = cgroup
+	}
+	return limit
+}
+
+func (t *SaturationTracker) ObserveLag(lag time.Duration) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.lag.Record(lag)
+}
+
+// Start samples generator health every second until returned function is called
+func (t *SaturationTracker) Start(options SaturationOptions) func() {
+	t.lock.Lock()
+	t.options = options.withDefaults()
+	t.lock.Unlock()
+	finish, finished := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		cpuLimit := availableCpu()
+		lastTime := time.Now()
+		lastCpu, cpuOk := processCpuTime()
+		var memStats runtime.MemStats
+		runtime.ReadMemStats(&memStats)
+		lastPause := memStats.PauseTotalNs
+		for {
+			select {
+			case <-finish:
+				return
+			case now := <-ticker.C:
+				runtime.ReadMemStats(&memStats)
+				wall := now.Sub(lastTime)
+				sample := SaturationSample{
+					Time:            now,
+					Goroutines:      runtime.NumGoroutine(),
+					GcPauseFraction: float64(memStats.PauseTotalNs-lastPause) / float64(wall.Nanoseconds()),
+					CpuLimit:        cpuLimit,
+				}
+				if cpu, ok := processCpuTime(); ok && cpuOk {
+					sample.CpuUsage = float64(cpu-lastCpu) / float64(wall) / cpuLimit
+					lastCpu = cpu
+				}
+				lastTime, lastPause = now, memStats.PauseTotalNs
+				t.record(sample)
+			}
+		}
+	}()
+	return func() {
+		close(finish)
+		<-finished
+	}
+}
+
+func (t *SaturationTracker) record(sample SaturationSample) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	sample.SchedulingLagP99 = t.lag.Quantile(0.99)
+	t.lag = NewLatencyHistogram()
+	if sample.SchedulingLagP99 > t.options.MaxSchedulingLag {
+		sample.Reasons = append(sample.Reasons, fmt.Sprintf("scheduling lag p99 %v > %v", roundLatency(sample.SchedulingLagP99), t.options.MaxSchedulingLag))
+	}
+	if sample.CpuUsage > t.options.MaxCpuUsage {
+		sample.Reasons = append(sample.Reasons, fmt.Sprintf("cpu usage %.0f%% of %.1f cores > %.0f%%", 100*sample.CpuUsage, sample.CpuLimit, 100*t.options.MaxCpuUsage))
+	}
+	if sample.GcPauseFraction > t.options.MaxGcPauseFraction {
+		sample.Reasons = append(sample.Reasons, fmt.Sprintf("gc pauses %.1f%% > %.1f%%", 100*sample.GcPauseFraction, 100*t.options.MaxGcPauseFraction))
+	}
+	if t.options.MaxGoroutines > 0 && sample.Goroutines > t.options.MaxGoroutines {
+		sample.Reasons = append(sample.Reasons, fmt.Sprintf("goroutines %v > %v", sample.Goroutines, t.options.MaxGoroutines))
+	}
+	t.samples = append(t.samples, sample)
+}
+
+// Samples returns all samples taken within [from, to) interval
+func (t *SaturationTracker) Samples(from, to time.Time) []SaturationSample {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	samples := make([]SaturationSample, 0)
+	for _, sample := range t.samples {
+		if !sample.Time.Before(from) && sample.Time.Before(to) {
+			samples = append(samples, sample)
+		}
+	}
+	return samples
+}
+
+// Last returns the most recent sample
+func (t *SaturationTracker) Last() (SaturationSample, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if len(t.samples) == 0 {
+		return SaturationSample{}, false
+	}
+	return t.samples[len(t.samples)-1], true
+}
+
+// SaturationReasons returns distinct reasons of saturation among the samples
+func SaturationReasons(samples []SaturationSample) []string {
+	reasons, seen := make([]string, 0), make(map[string]struct{})
+	for _, sample := range samples {
+		for _, reason := range sample.Reasons {
+			kind := strings.SplitN(reason, " ", 2)[0]
+			if _, ok := seen[kind]; !ok {
+				seen[kind] = struct{}{}
+				reasons = append(reasons, reason)
+			}
+		}
+	}
+	return reasons
+}
+
+// SaturatedFraction returns fraction of samples during which generator was saturated
+func SaturatedFraction(samples []SaturationSample) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	saturated := 0
+	for _, sample := range samples {
+		if sample.Saturated() {
+			saturated++
+		}
+	}
+	return float64(saturated) / float64(len(samples))
+}
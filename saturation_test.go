@@ -0,0 +1,116 @@
+package gostress
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSaturationTrackerRecord(t *testing.T) {
+	tests := []struct {
+		name    string
+		options SaturationOptions
+		lag     time.Duration
+		sample  SaturationSample
+		reasons []string
+	}{
+		{name: "healthy", lag: time.Millisecond, sample: SaturationSample{CpuUsage: 0.5, CpuLimit: 2, GcPauseFraction: 0.01, Goroutines: 100}},
+		{name: "scheduling lag", lag: 100 * time.Millisecond, reasons: []string{"scheduling lag p99 100ms > 50ms"}},
+		{name: "cpu", sample: SaturationSample{CpuUsage: 0.95, CpuLimit: 2}, reasons: []string{"cpu usage 95% of 2.0 cores > 90%"}},
+		{name: "gc", sample: SaturationSample{GcPauseFraction: 0.1}, reasons: []string{"gc pauses 10.0% > 5.0%"}},
+		{name: "goroutines disabled", sample: SaturationSample{Goroutines: 100000}},
+		{
+			name:    "goroutines",
+			options: SaturationOptions{MaxGoroutines: 10},
+			sample:  SaturationSample{Goroutines: 11},
+			reasons: []string{"goroutines 11 > 10"},
+		},
+		{
+			name:    "custom thresholds",
+			options: SaturationOptions{MaxSchedulingLag: time.Second, MaxCpuUsage: 0.5},
+			lag:     100 * time.Millisecond,
+			sample:  SaturationSample{CpuUsage: 0.6, CpuLimit: 1},
+			reasons: []string{"cpu usage 60% of 1.0 cores > 50%"},
+		},
+		{
+			name:    "several",
+			lag:     time.Second,
+			sample:  SaturationSample{CpuUsage: 1, CpuLimit: 4, GcPauseFraction: 0.2},
+			reasons: []string{"scheduling lag p99 1s > 50ms", "cpu usage 100% of 4.0 cores > 90%", "gc pauses 20.0% > 5.0%"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tracker := NewSaturationTracker()
+			tracker.options = test.options.withDefaults()
+			if test.lag > 0 {
+				tracker.ObserveLag(test.lag)
+			}
+			tracker.record(test.sample)
+			sample, ok := tracker.Last()
+			require.True(t, ok)
+			assert.Equal(t, test.reasons, sample.Reasons)
+			assert.Equal(t, len(test.reasons) > 0, sample.Saturated())
+		})
+	}
+}
+
+func TestSaturatedFraction(t *testing.T) {
+	saturated := SaturationSample{Reasons: []string{"gc pauses 10.0% > 5.0%"}}
+	tests := []struct {
+		samples  []SaturationSample
+		fraction float64
+	}{
+		{samples: nil, fraction: 0},
+		{samples: []SaturationSample{{}, {}}, fraction: 0},
+		{samples: []SaturationSample{saturated, {}, {}, saturated}, fraction: 0.5},
+		{samples: []SaturationSample{saturated}, fraction: 1},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.fraction, SaturatedFraction(test.samples))
+	}
+}
+
+func TestSaturationReasons(t *testing.T) {
+	samples := []SaturationSample{
+		{},
+		{Reasons: []string{"cpu usage 95% of 2.0 cores > 90%"}},
+		{Reasons: []string{"cpu usage 99% of 2.0 cores > 90%", "gc pauses 10.0% > 5.0%"}},
+		{Reasons: []string{"gc pauses 20.0% > 5.0%", "scheduling lag p99 1s > 50ms"}},
+	}
+	assert.Equal(t, []string{"cpu usage 95% of 2.0 cores > 90%", "gc pauses 10.0% > 5.0%", "scheduling lag p99 1s > 50ms"}, SaturationReasons(samples))
+	assert.Empty(t, SaturationReasons(nil))
+}
+
+func TestCgroupCpuLimit(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		limit float64
+		ok    bool
+	}{
+		{name: "v2", files: map[string]string{"cpu.max": "150000 100000\n"}, limit: 1.5, ok: true},
+		{name: "v2 unlimited", files: map[string]string{"cpu.max": "max 100000\n"}},
+		{name: "v2 malformed", files: map[string]string{"cpu.max": "150000\n"}},
+		{name: "v2 takes precedence", files: map[string]string{"cpu.max": "max 100000\n", "cpu/cpu.cfs_quota_us": "200000\n", "cpu/cpu.cfs_period_us": "100000\n"}},
+		{name: "v1", files: map[string]string{"cpu/cpu.cfs_quota_us": "50000\n", "cpu/cpu.cfs_period_us": "100000\n"}, limit: 0.5, ok: true},
+		{name: "v1 unlimited", files: map[string]string{"cpu/cpu.cfs_quota_us": "-1\n", "cpu/cpu.cfs_period_us": "100000\n"}},
+		{name: "v1 without period", files: map[string]string{"cpu/cpu.cfs_quota_us": "50000\n"}},
+		{name: "no cgroup"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := t.TempDir()
+			for name, content := range test.files {
+				require.Nil(t, os.MkdirAll(filepath.Dir(filepath.Join(root, name)), 0o755))
+				require.Nil(t, os.WriteFile(filepath.Join(root, name), []byte(content), 0o644))
+			}
+			limit, ok := cgroupCpuLimit(root)
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.limit, limit)
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package gostress
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/zap/zaptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"text/tabwriter"
+)
+
+// Scenario is a named stress which can be run from the gostress cli or as a subtest with RunScenarios
+type Scenario struct {
+	Name        string
+	Description string
+	// Options are defaults of the scenario which can be overridden from the command line
+	Options GoStressOptions
+	F       StressFn
+}
+
+var (
+	scenariosLock sync.Mutex
+	scenarios     = make(map[string]Scenario)
+)
+
+// RegisterScenario makes scenario available for the cli; it is intended to be called from init and panics on duplicates
+func RegisterScenario(scenario Scenario) {
+	scenariosLock.Lock()
+	defer scenariosLock.Unlock()
+	if scenario.Name == "" || scenario.F == nil {
+		panic(fmt.Errorf("scenario must have name and stress function"))
+	}
+	if _, ok := scenarios[scenario.Name]; ok {
+		panic(fmt.Errorf("scenario %v is already registered", scenario.Name))
+	}
+	scenarios[scenario.Name] = scenario
+}
+
+func LookupScenario(name string) (Scenario, bool) {
+	scenariosLock.Lock()
+	defer scenariosLock.Unlock()
+	scenario, ok := scenarios[name]
+	return scenario, ok
+}
+
+// Scenarios returns all registered scenarios ordered by name
+func Scenarios() []Scenario {
+	scenariosLock.Lock()
+	defer scenariosLock.Unlock()
+	registered := make([]Scenario, 0, len(scenarios))
+	for _, scenario := range scenarios {
+		registered = append(registered, scenario)
+	}
+	sort.Slice(registered, func(i, j int) bool { return registered[i].Name < registered[j].Name })
+	return registered
+}
+
+type (
+	SubtestOptions struct {
+		// Parallel runs all scenarios at the same time (scenarios without MetricsPort serve metrics on distinct free ports)
+		Parallel bool
+		// Namespace runs every scenario in its own k8s pod with RunK8s, scenarios are run locally when empty
+		Namespace string
+		PodOpts   []PodOpts
+	}
+
+	ScenarioResult struct {
+		Name   string
+		Failed bool
+		// Skipped is set when the subtest was filtered out with -test.run
+		Skipped bool
+		Result  Result
+	}
+)
+
+// RunScenarios runs every scenario as a t.Run subtest with its own metrics, pass/fail status and reports,
+// and logs combined summary of all scenarios; results are returned in the order of scenarios
+func RunScenarios(t *testing.T, scenarios []Scenario, options SubtestOptions) []ScenarioResult {
+	results := make([]ScenarioResult, len(scenarios))
+	ctx := context.Background()
+	if options.Namespace != "" {
+		// RunLocal handles signals by itself, but the k8s launcher needs the context to forward them to the pods
+		ctx = ShutdownCtx()
+	}
+	run := func(i int) {
+		scenario := scenarios[i]
+		results[i] = ScenarioResult{Name: scenario.Name, Skipped: true}
+		t.Run(scenario.Name, func(t *testing.T) {
+			results[i].Skipped = false
+			// deferred, so failures reported before the panic of the k8s launcher are accounted as well
+			defer func() { results[i].Failed = t.Failed() }()
+			stress, shutdown := NewGoStress(t, scenario.Options, scenario.F)
+			defer shutdown()
+			if options.Namespace != "" {
+				results[i].Result = stress.RunK8s(ctx, options.Namespace, options.PodOpts...)
+			} else {
+				results[i].Result = stress.RunLocal(ctx)
+			}
+		})
+	}
+	if options.Parallel {
+		// t.Run blocks until the subtest is finished, so concurrent calls run subtests in parallel without t.Parallel,
+		// which would otherwise postpone subtests until the parent test returns
+		subtests := sync.WaitGroup{}
+		for i := range scenarios {
+			subtests.Add(1)
+			go func(i int) {
+				defer subtests.Done()
+				run(i)
+			}(i)
+		}
+		subtests.Wait()
+	} else {
+		for i := range scenarios {
+			run(i)
+		}
+	}
+	zaptest.NewLogger(t).Sugar().Infof("summary of %v scenarios:\n%v", len(results), ScenariosSummary(results))
+	return results
+}
+
+// ScenariosSummary formats results of the scenarios as a table
+func ScenariosSummary(results []ScenarioResult) string {
+	summary := strings.Builder{}
+	writer := tabwriter.NewWriter(&summary, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintf(writer, "scenario\tstatus\tsent\terrors\tskipped\tachieved rps\tp50\tp99\n")
+	for _, scenario := range results {
+		status := "ok"
+		if scenario.Skipped {
+			status = "skipped"
+		} else if scenario.Failed {
+			status = "failed"
+		} else if scenario.Result.Aborted {
+			status = "aborted"
+		}
+		total := scenario.Result.Total
+		latency := total.Latency["success"]
+		_, _ = fmt.Fprintf(
+			writer, "%v\t%v\t%v\t%v\t%v\t%.2f\t%v\t%v\n",
+			scenario.Name, status, total.Sent, total.Errors, total.Skipped, total.AchievedRps, roundLatency(latency.P50), roundLatency(latency.P99),
+		)
+	}
+	_ = writer.Flush()
+	return strings.TrimSuffix(summary.String(), "\n")
+}
@@ -0,0 +1,156 @@
+package gostress
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func init() {
+	RegisterScenario(Scenario{
+		Name:        "test-noop",
+		Description: "does nothing",
+		Options:     GoStressOptions{Schedule: LoadSchedule{{Rps: 20, Workers: 2, Duration: time.Second}}},
+		F:           func(ctx RequestContext) error { return nil },
+	})
+	RegisterScenario(Scenario{
+		Name:        "test-slow",
+		Description: "sleeps 20ms",
+		F: func(ctx RequestContext) error {
+			time.Sleep(20 * time.Millisecond)
+			return nil
+		},
+	})
+}
+
+func TestScenarioRegistry(t *testing.T) {
+	scenario, ok := LookupScenario("test-noop")
+	require.True(t, ok)
+	assert.Equal(t, "does nothing", scenario.Description)
+	_, ok = LookupScenario("test-missing")
+	assert.False(t, ok)
+	assert.Panics(t, func() { RegisterScenario(Scenario{Name: "test-noop", F: scenario.F}) })
+	assert.Panics(t, func() { RegisterScenario(Scenario{Name: "test-nil"}) })
+}
+
+func TestCliList(t *testing.T) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	assert.Equal(t, cliExitOk, RunCli([]string{"list"}, stdout, stderr))
+	assert.Contains(t, stdout.String(), "test-noop  does nothing\n")
+	assert.Contains(t, stdout.String(), "test-slow  sleeps 20ms\n")
+}
+
+func TestCliNoScenarios(t *testing.T) {
+	scenariosLock.Lock()
+	registered := scenarios
+	scenarios = make(map[string]Scenario)
+	scenariosLock.Unlock()
+	defer func() {
+		scenariosLock.Lock()
+		scenarios = registered
+		scenariosLock.Unlock()
+	}()
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	assert.Equal(t, cliExitOk, RunCli([]string{"list"}, stdout, stderr))
+	assert.Empty(t, stdout.String())
+	assert.Equal(t, cliNoScenarios, stderr.String())
+
+	stderr.Reset()
+	assert.Equal(t, cliExitUsage, RunCli([]string{"run", "test-noop"}, stdout, stderr))
+	assert.Equal(t, "unknown scenario test-noop, "+cliNoScenarios, stderr.String())
+}
+
+func TestCliRun(t *testing.T) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	assert.Equal(t, cliExitUsage, RunCli([]string{"run"}, stdout, stderr))
+	assert.Equal(t, cliExitUsage, RunCli([]string{"run", "test-missing"}, stdout, stderr))
+	assert.Equal(t, cliExitUsage, RunCli([]string{"run", "test-slow"}, stdout, stderr))
+	assert.Equal(t, cliExitUsage, RunCli([]string{"run", "test-noop", "-schedule", "invalid"}, stdout, stderr))
+	assert.Equal(t, cliExitUsage, RunCli([]string{"run", "test-noop", "-profile", "unknown"}, stdout, stderr))
+	assert.Equal(t, cliExitUsage, RunCli([]string{"run", "test-slow", "-profile", "load"}, stdout, stderr))
+
+	result := filepath.Join(t.TempDir(), "result.json")
+	report := filepath.Join(t.TempDir(), "report.md")
+	assert.Equal(t, cliExitOk, RunCli([]string{"run", "test-noop", "-result", result, "-report", "markdown:" + report}, stdout, stderr))
+	parsed, err := ReadResult(result)
+	require.Nil(t, err)
+	require.Len(t, parsed.Stages, 1)
+	assert.Equal(t, LoadParams{Rps: 20, Workers: 2, Duration: time.Second}, parsed.Stages[0].Params)
+	// intervals between requests are random with the mean of 1/rps, so 20 rps over 1s yields 20 requests give or take a few
+	assert.InEpsilon(t, 20, parsed.Total.Sent+parsed.Total.Skipped, 0.5)
+	assert.Equal(t, int64(0), parsed.Total.Errors)
+	content, err := os.ReadFile(report)
+	require.Nil(t, err)
+	assert.Contains(t, string(content), "### gostress: test-noop")
+
+	// every request sleeps 20ms, so p99 threshold is exceeded by an order of magnitude
+	assert.Equal(t, cliExitFailed, RunCli([]string{"run", "test-slow", "-schedule", "20:2:1s", "-max-p99", "1ms"}, stdout, stderr))
+}
+
+func TestRunScenarios(t *testing.T) {
+	scenarios := []Scenario{
+		{
+			Name:    "fast",
+			Options: GoStressOptions{WorkerTimeout: time.Second, Schedule: LoadSchedule{{Rps: 20, Workers: 2, Duration: time.Second}}},
+			F:       func(ctx RequestContext) error { return nil },
+		},
+		{
+			Name:    "slow",
+			Options: GoStressOptions{WorkerTimeout: time.Second, Schedule: LoadSchedule{{Rps: 10, Workers: 4, Duration: time.Second}}},
+			F: func(ctx RequestContext) error {
+				time.Sleep(20 * time.Millisecond)
+				return nil
+			},
+		},
+	}
+	for _, parallel := range []bool{false, true} {
+		parallel := parallel
+		t.Run(fmt.Sprintf("parallel=%v", parallel), func(t *testing.T) {
+			startTime := time.Now()
+			results := RunScenarios(t, scenarios, SubtestOptions{Parallel: parallel})
+			require.Len(t, results, 2)
+			for i, result := range results {
+				assert.Equal(t, scenarios[i].Name, result.Name)
+				assert.False(t, result.Failed)
+				assert.False(t, result.Skipped)
+				assert.Equal(t, t.Name()+"/"+scenarios[i].Name, result.Result.Name)
+			}
+			// every scenario is paced by its own schedule, so concurrent scenarios don't share or steal the load
+			for i, result := range results {
+				stage := scenarios[i].Options.Schedule[0]
+				require.Len(t, result.Result.Stages, 1)
+				assert.Equal(t, stage, result.Result.Stages[0].Params)
+				expected := float64(stage.Rps) * stage.Duration.Seconds()
+				assert.InEpsilon(t, expected, result.Result.Total.Sent+result.Result.Total.Skipped, 0.5, scenarios[i].Name)
+			}
+			assert.GreaterOrEqual(t, results[1].Result.Total.Latency["success"].P50, 20*time.Millisecond)
+			assert.Less(t, results[0].Result.Total.Latency["success"].P99, 20*time.Millisecond)
+			if parallel {
+				assert.Less(t, time.Since(startTime), 2*time.Second)
+			}
+		})
+	}
+	summary := ScenariosSummary([]ScenarioResult{{Name: "fast", Result: Result{Total: StageResult{Sent: 20}}}, {Name: "slow", Skipped: true}})
+	assert.Contains(t, summary, "fast      ok       20")
+	assert.Contains(t, summary, "slow      skipped")
+}
+
+func TestTestRunPattern(t *testing.T) {
+	assert.Equal(t, "^TestStress$", testRunPattern("TestStress"))
+	assert.Equal(t, `^TestStress$/^read\+write$`, testRunPattern("TestStress/read+write"))
+	pattern := regexp.MustCompile(strings.Split(testRunPattern("TestStress/read+write"), "/")[1])
+	assert.True(t, pattern.MatchString("read+write"))
+	assert.False(t, pattern.MatchString("read+write-heavy"))
+}
+
+func TestK8sNormalize(t *testing.T) {
+	assert.Equal(t, "gostress-teststress-parallel-true-read-write-01-1a2b3c4d", K8sNormalize("gostress-TestStress/parallel=true/read+write#01-1a2b3c4d"))
+}
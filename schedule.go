@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"go.uber.org/zap"
 	"math/rand"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -13,14 +15,59 @@ import (
 type (
 	LoadSchedule []LoadParams
 	LoadParams   struct {
-		Rps      int
-		Workers  int
-		Duration time.Duration
+		// Name is an optional name of the stage which is attached to metrics and reports
+		Name     string        `json:"name,omitempty"`
+		Rps      int           `json:"rps"`
+		Workers  int           `json:"workers"`
+		Duration time.Duration `json:"duration"`
+	}
+	// Stage identifies segment of the LoadSchedule during which request was triggered
+	Stage struct {
+		Index int    `json:"index"`
+		Name  string `json:"name,omitempty"`
+	}
+	Runner struct {
+		Id      int64
+		Metrics *Metrics
 	}
-	Runner struct{ Id int64 }
 )
 
-func NewRunner() *Runner { return &Runner{} }
+func NewRunner(metrics *Metrics) *Runner { return &Runner{Metrics: metrics} }
+
+// ParseLoadSchedule parses comma-separated list of stages in the "[name=]rps:workers:duration" form, e.g. "warmup=10:1:30s,100:8:5m"
+func ParseLoadSchedule(schedule string) (LoadSchedule, error) {
+	parsed := make(LoadSchedule, 0)
+	for _, stage := range strings.Split(schedule, ",") {
+		var params LoadParams
+		stage = strings.TrimSpace(stage)
+		if name, rest, ok := strings.Cut(stage, "="); ok {
+			params.Name, stage = name, rest
+		}
+		tokens := strings.Split(stage, ":")
+		if len(tokens) != 3 {
+			return nil, fmt.Errorf("invalid stage %q: expected [name=]rps:workers:duration", stage)
+		}
+		var err error
+		if params.Rps, err = strconv.Atoi(tokens[0]); err != nil {
+			return nil, fmt.Errorf("invalid rps in stage %q: %w", stage, err)
+		}
+		if params.Workers, err = strconv.Atoi(tokens[1]); err != nil {
+			return nil, fmt.Errorf("invalid workers in stage %q: %w", stage, err)
+		}
+		if params.Duration, err = time.ParseDuration(tokens[2]); err != nil {
+			return nil, fmt.Errorf("invalid duration in stage %q: %w", stage, err)
+		}
+		parsed = append(parsed, params)
+	}
+	return parsed, nil
+}
+
+func (s Stage) String() string {
+	if s.Name == "" {
+		return fmt.Sprintf("stage %v", s.Index)
+	}
+	return fmt.Sprintf("stage %v (%v)", s.Index, s.Name)
+}
 
 func (r *Runner) Trigger(work chan<- Id) (Id, bool) {
 	id := atomic.AddInt64(&r.Id, 1)
@@ -32,6 +79,9 @@ func (r *Runner) Trigger(work chan<- Id) (Id, bool) {
 	}
 }
 
+// maxPacingDebt is a maximum lag of the rater after which it stops catching up with the intended trigger times
+const maxPacingDebt = time.Second
+
 func (r *Runner) RunSimpleSchedule(ctx context.Context, start, end LoadParams, pool *WorkerPool, logger *zap.SugaredLogger) {
 	logger.Infof("start simple schedule: start=%v, end=%v", start, end)
 	startTime := time.Now()
@@ -44,6 +94,7 @@ func (r *Runner) RunSimpleSchedule(ctx context.Context, start, end LoadParams, p
 	for i := 0; i < ratersCount; i++ {
 		raters.Add(1)
 		go func() {
+			intended := time.Now()
 			for !Finished(ctx) {
 				elapsed := time.Since(startTime)
 				if elapsed >= start.Duration {
@@ -51,18 +102,22 @@ func (r *Runner) RunSimpleSchedule(ctx context.Context, start, end LoadParams, p
 				}
 				currentParams := interpolate(start, end, elapsed)
 				pool.Adjust(currentParams.Workers)
-				ExpectedRpsGauge.Set(float64(currentParams.Rps))
-				ExpectedWorkersGauge.Set(float64(currentParams.Workers))
-				CurrentWorkersGauge.Set(float64(len(pool.Workers)))
+				r.Metrics.ExpectedRpsGauge.Set(float64(currentParams.Rps))
+				r.Metrics.ExpectedWorkersGauge.Set(float64(currentParams.Workers))
+				r.Metrics.CurrentWorkersGauge.Set(float64(pool.Size()))
+				lag := time.Since(intended)
 				id, ok := r.Trigger(pool.Work)
-				if ok {
-					SentRequestCounter.Inc()
-				} else {
+				r.Metrics.ObserveTrigger(lag, ok)
+				if !ok {
 					logger.Warnf("request %v was skipped because there were no free worker", id)
-					SkippedRequestCounter.Inc()
 				}
 				sleepTime := time.Duration(2 * rand.Float64() / float64(currentParams.Rps) * float64(time.Second) * float64(ratersCount))
-				time.Sleep(sleepTime)
+				intended = intended.Add(sleepTime)
+				if time.Since(intended) > maxPacingDebt {
+					// rater fell too far behind, so don't try to catch up with burst of requests
+					intended = time.Now()
+				}
+				time.Sleep(time.Until(intended))
 			}
 			raters.Done()
 		}()
@@ -70,17 +125,31 @@ func (r *Runner) RunSimpleSchedule(ctx context.Context, start, end LoadParams, p
 	raters.Wait()
 }
 
-func (r *Runner) RunSchedule(ctx context.Context, schedule LoadSchedule, pool *WorkerPool, logger *zap.SugaredLogger) error {
+func (r *Runner) RunSchedule(ctx context.Context, schedule LoadSchedule, pool *WorkerPool, logger *zap.SugaredLogger) ([]StageResult, error) {
 	logger.Infof("start schedule: %v", schedule)
+	r.Metrics.ResetRates()
+	stages := make([]StageResult, 0, len(schedule))
 	for i := 0; i < len(schedule) && !Finished(ctx); i++ {
 		start, end := schedule[i], schedule[i]
 		if i+1 < len(schedule) {
 			end = schedule[i+1]
 		}
+		current := Stage{Index: i, Name: start.Name}
+		r.Metrics.SetStage(current)
+		r.Metrics.Events.Emit(EventStageStart, fmt.Sprintf("%v started: %v", current, start.String()), "stage", strconv.Itoa(i), "stage_name", start.Name)
+		before := r.Metrics.Snapshot()
 		r.RunSimpleSchedule(ctx, start, end, pool, logger)
+		stage := r.Metrics.Snapshot().StageResult(before)
+		stage.Index, stage.Params = i, start
+		stages = append(stages, stage)
+		r.Metrics.Events.Emit(
+			EventStageEnd, fmt.Sprintf("%v finished: sent %v, errors %v, skipped %v", current, stage.Sent, stage.Errors, stage.Skipped),
+			"stage", strconv.Itoa(i), "stage_name", start.Name,
+		)
 	}
 	if Finished(ctx) {
-		return fmt.Errorf("forcibly finish schedule: context was cancelled")
+		r.Metrics.Events.Emit(EventAbort, fmt.Sprintf("schedule aborted: %v", context.Cause(ctx)))
+		return stages, fmt.Errorf("forcibly finish schedule: %w", context.Cause(ctx))
 	}
-	return nil
+	return stages, nil
 }
@@ -10,16 +10,32 @@ import (
 )
 
 func TestFairLoadGeneration(t *testing.T) {
-	r := NewRunner()
+	metrics := NewMetrics(t.Name(), nil)
+	r := NewRunner(metrics)
 	l1 := LoadParams{Rps: 500, Workers: 10, Duration: 10 * time.Second}
 	requests := int64(0)
 	logger := zaptest.NewLogger(t).Sugar()
-	pool := NewWorkerPool(time.Second, logger, func(ctx RequestContext) error {
+	pool := NewWorkerPool(time.Second, metrics, logger, func(ctx RequestContext) error {
 		atomic.AddInt64(&requests, 1)
 		return nil
 	})
 	r.RunSimpleSchedule(context.Background(), l1, l1, pool, logger)
-	t.Logf("requests: %v", requests)
-	assert.Greater(t, requests, int64(4900))
-	assert.Less(t, requests, int64(5100))
+	assert.True(t, pool.Drain(2*time.Second))
+	total := atomic.LoadInt64(&requests)
+	t.Logf("requests: %v", total)
+	assert.Greater(t, total, int64(4900))
+	assert.Less(t, total, int64(5100))
+}
+
+func TestParseLoadSchedule(t *testing.T) {
+	schedule, err := ParseLoadSchedule("warmup=10:1:30s, 100:8:5m")
+	assert.Nil(t, err)
+	assert.Equal(t, LoadSchedule{
+		{Name: "warmup", Rps: 10, Workers: 1, Duration: 30 * time.Second},
+		{Rps: 100, Workers: 8, Duration: 5 * time.Minute},
+	}, schedule)
+	for _, invalid := range []string{"", "10:1", "a:1:1s", "10:b:1s", "10:1:c"} {
+		_, err := ParseLoadSchedule(invalid)
+		assert.NotNil(t, err, invalid)
+	}
 }
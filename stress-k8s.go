@@ -2,10 +2,13 @@ package gostress
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
 )
@@ -13,46 +16,71 @@ import (
 const (
 	GoStressEnv    = "GOSTRESSENV"
 	GoStressEnvK8s = "K8S"
+	// GoStressResultFileEnv is a path where the remote run writes its result, so the launcher can fetch it after completion
+	GoStressResultFileEnv = "GOSTRESS_RESULT_FILE"
+	// GoStressMetricsPortEnv is a port of the metrics endpoint advertised by the pod annotation, it is used when MetricsPort isn't set
+	GoStressMetricsPortEnv = "GOSTRESS_METRICS_PORT"
+	goStressResultFile     = "gostress-result.json"
 )
 
 var (
 	shutdownTimeout = 1 * time.Minute
 	waitTimeout     = 1 * time.Minute
 	waitInterval    = 200 * time.Millisecond
+	// podGracePeriodMargin is added to the drain timeout to cover process startup and result upload
+	podGracePeriodMargin = 10 * time.Second
 )
 
+// k8sInvalidChars are characters which are not allowed in the pod name (subtest names may contain any of them)
+var k8sInvalidChars = regexp.MustCompile(`[^a-z0-9-]`)
+
 func K8sNormalize(s string) string {
-	s = strings.ReplaceAll(s, "/", "-")
-	s = strings.ReplaceAll(s, ".", "-")
-	s = strings.ReplaceAll(s, "_", "-")
-	return strings.ToLower(s)
+	return k8sInvalidChars.ReplaceAllString(strings.ToLower(s), "-")
 }
 
-func (s *Stress) RunK8s(ctx context.Context, namespace string, modifiers ...PodOpts) {
-	if os.Getenv(GoStressEnv) == GoStressEnvK8s {
-		s.Logger.Infof("detected k8s environment, run test locally")
-		s.RunLocal(ctx)
-		return
+// RunK8s runs the stress in the one-time k8s pod
+// Result of the remote run is fetched from the pod, reported and checked locally, so the launching test fails on remote results
+func (s *Stress) RunK8s(ctx context.Context, namespace string, modifiers ...PodOpts) Result {
+	if os.Getenv(GoStressEnv) == GoStressEnvK8s || s.Options.DryRun {
+		s.Logger.Infof("detected k8s environment or dry run, run test locally")
+		return s.RunLocal(ctx)
+	}
+	if s.invalid != nil {
+		return s.invalidResult()
 	}
 	s.Logger.Infof("run k8s stress")
-	s.runK8s(ctx, namespace, false, modifiers...)
+	return s.runK8s(ctx, namespace, false, modifiers...)
 }
 
-func (s *Stress) RunK8sDetached(ctx context.Context, namespace string, modifiers ...PodOpts) {
-	if os.Getenv(GoStressEnv) == GoStressEnvK8s {
-		s.Logger.Infof("detected k8s environment, run test locally")
-		s.RunLocal(ctx)
-		return
+// RunK8sDetached starts the stress in the one-time k8s pod without waiting for its completion
+// Result returned to the launcher contains only timings of the launch
+func (s *Stress) RunK8sDetached(ctx context.Context, namespace string, modifiers ...PodOpts) Result {
+	if os.Getenv(GoStressEnv) == GoStressEnvK8s || s.Options.DryRun {
+		s.Logger.Infof("detected k8s environment or dry run, run test locally")
+		return s.RunLocal(ctx)
+	}
+	if s.invalid != nil {
+		return s.invalidResult()
 	}
 	s.Logger.Infof("run k8s stress in detached mode")
-	s.runK8s(ctx, namespace, true, modifiers...)
+	return s.runK8s(ctx, namespace, true, modifiers...)
 }
 
-func (s *Stress) runK8s(ctx context.Context, namespace string, detach bool, modifiers ...PodOpts) {
+func (s *Stress) runK8s(ctx context.Context, namespace string, detach bool, modifiers ...PodOpts) Result {
+	result := Result{Name: s.Name, StartTime: time.Now()}
 	kube, err := NewKubeContext(s.Logger)
 	if err != nil {
 		panic(fmt.Errorf("unable to create k8s context: %w", err))
 	}
+	kube.Events = s.Metrics.Events
+	s.openEventLog()
+	defer func() {
+		if err := s.Metrics.Events.Close(); err != nil {
+			s.Logger.Errorf("unable to close event log: %v", err)
+		}
+	}()
+	// kubelet gives the remote run its drain timeout after SIGTERM (explicit modifiers take precedence)
+	modifiers = append([]PodOpts{WithTerminationGracePeriod(s.drainTimeout() + podGracePeriodMargin)}, modifiers...)
 	podObject := kube.GetPodObject(namespace, fmt.Sprintf("gostress-%v-%v", s.Name, s.Nonce), modifiers...)
 	s.Logger.Infof("ready to create one time pod in namespace %v", namespace)
 	kubePod, err := kube.CreateOneTimePod(ctx, podObject)
@@ -96,8 +124,114 @@ func (s *Stress) runK8s(ctx context.Context, namespace string, detach bool, modi
 	if err != nil {
 		panic(fmt.Errorf("unable to get relative path for %v against %v: %w", cwd, root, err))
 	}
-	_, err = workspace.Exec(ctx, fmt.Sprintf("%v -test.run %v -test.v", path.Join(workspace.Directory(), rel, workspace.Name()), s.Name), detach)
+	resultFile := path.Join(workspace.Directory(), goStressResultFile)
+	args := make([]string, 0)
+	for _, arg := range s.Overrides.Args() {
+		args = append(args, shellQuote(arg))
+	}
+	// pid is recorded, so SIGTERM of the pod (or interruption of the launcher) is forwarded to the remote run
+	// $BASHPID is used because in detached mode the command runs in the background subshell, where $$ is a pid of the parent bash
+	command := strings.TrimSpace(fmt.Sprintf(
+		"echo $BASHPID > %v && %v=%v exec %v -test.run %v -test.v %v",
+		goStressPidFile, GoStressResultFileEnv, resultFile, path.Join(workspace.Directory(), rel, workspace.Name()), shellQuote(testRunPattern(s.Name)), strings.Join(args, " "),
+	))
+	if !detach {
+		var stopForwarding func()
+		ctx, stopForwarding = s.forwardShutdown(ctx, workspace)
+		defer stopForwarding()
+	}
+	_, err = workspace.Exec(ctx, command, detach)
+	if detach {
+		if err != nil {
+			panic(err)
+		}
+		result.EndTime = time.Now()
+		result.Duration = result.EndTime.Sub(result.StartTime)
+		return result
+	}
 	if err != nil {
-		panic(err)
+		s.T.Errorf("remote stress test failed: %v", err)
+	}
+	remote, fetchErr := s.fetchRemoteResult(ctx, workspace, resultFile, result.StartTime)
+	if fetchErr != nil {
+		// remote run crashed or was killed before writing the result, so there is nothing to report
+		s.T.Errorf("%v", fetchErr)
+		result.EndTime = time.Now()
+		result.Duration = result.EndTime.Sub(result.StartTime)
+		result.Aborted, result.AbortReason = true, fetchErr.Error()
+		return result
+	}
+	return remote
+}
+
+// testRunPattern returns -test.run pattern which selects exactly the test (or subtest) with the given name
+// Every level of the name is matched separately by go test, so levels are quoted and anchored one by one
+func testRunPattern(name string) string {
+	levels := strings.Split(name, "/")
+	for i, level := range levels {
+		levels[i] = "^" + regexp.QuoteMeta(level) + "$"
+	}
+	return strings.Join(levels, "/")
+}
+
+// forwardShutdown returns context which isn't cancelled together with ctx: instead, cancellation of ctx sends SIGTERM to the remote run,
+// so it drains and writes its result; context is cancelled only when the remote run didn't finish within the drain timeout
+func (s *Stress) forwardShutdown(ctx context.Context, workspace *KubePodWorkspace) (context.Context, func()) {
+	remoteCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+			return
+		}
+		s.Logger.Warnf("launcher is interrupted, sending SIGTERM to the remote run")
+		signalCtx, cancelSignal := context.WithTimeout(remoteCtx, waitTimeout)
+		if _, err := workspace.Exec(signalCtx, fmt.Sprintf("kill -TERM $(cat %v)", goStressPidFile), false); err != nil {
+			s.Logger.Errorf("unable to send SIGTERM to the remote run: %v", err)
+		}
+		cancelSignal()
+		timer := time.NewTimer(s.drainTimeout() + podGracePeriodMargin)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			s.Logger.Errorf("remote run didn't finish within %v after SIGTERM", s.drainTimeout()+podGracePeriodMargin)
+			cancel()
+		case <-done:
+		}
+	}()
+	return remoteCtx, func() {
+		close(done)
+		cancel()
+	}
+}
+
+// fetchRemoteResult reads result of the remote run and reports it locally in the same way as RunLocal does
+func (s *Stress) fetchRemoteResult(ctx context.Context, workspace *KubePodWorkspace, resultFile string, startTime time.Time) (Result, error) {
+	var result Result
+	content, err := workspace.ReadFile(ctx, resultFile)
+	if err != nil {
+		return result, fmt.Errorf("unable to fetch remote result: %w", err)
+	}
+	if err := json.Unmarshal(content, &result); err != nil {
+		return result, fmt.Errorf("unable to parse remote result: %w", err)
+	}
+	s.Logger.Infof("fetched result of the remote run from %v", resultFile)
+	// pod lifecycle events are known only to the launcher, so they are merged with the events of the remote run
+	result.Events = append(result.Events, s.Metrics.Events.Events(startTime, time.Now())...)
+	sort.SliceStable(result.Events, func(i, j int) bool { return result.Events[i].Time.Before(result.Events[j].Time) })
+	snapshot := ReportSnapshot{Name: result.Name, Profile: result.Profile, Time: result.EndTime, Elapsed: result.Duration}
+	ReportResult(snapshot.WithResult(result), s.reporters(), s.Logger)
+	if result.Invalid {
+		s.T.Errorf("stress run is invalid: %v", result.InvalidReason)
+	}
+	if result.SetupError != "" {
+		s.T.Errorf("remote %v", result.SetupError)
+	}
+	if result.TeardownError != "" {
+		s.T.Errorf("remote %v", result.TeardownError)
 	}
+	s.checkThresholds(result)
+	s.checkBaseline(result)
+	return result, nil
 }
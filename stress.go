@@ -2,77 +2,570 @@ package gostress
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
+	"net"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
 
 type (
 	Stress struct {
-		Name           string
-		Nonce          string
-		Workers        *WorkerPool
-		Runner         *Runner
-		Schedule       LoadSchedule
-		ReportInterval time.Duration
-		Logger         *zap.SugaredLogger
-		MetricsPort    int
+		Name            string
+		Nonce           string
+		Workers         *WorkerPool
+		Runner          *Runner
+		Metrics         *Metrics
+		Schedule        LoadSchedule
+		ReportInterval  time.Duration
+		Logger          *zap.SugaredLogger
+		MetricsPort     int
+		ResultsFile     string
+		ResultsInterval time.Duration
+		HtmlReportFile  string
+		Options         GoStressOptions
+		T               TestingT
+		Tracer          *OtlpTracer
+		Grafana         *GrafanaAnnotator
+		// Overrides applied to the options from the command line, they are forwarded to the remote run
+		Overrides Overrides
+		// invalid is an error of the options resolution, such stress is aborted without load
+		invalid error
+	}
+
+	// TestingT receives failures of the run (violated thresholds, regressions, invalid results)
+	TestingT interface {
+		Errorf(format string, args ...any)
 	}
 
 	GoStressOptions struct {
 		WorkerTimeout  time.Duration
 		Schedule       LoadSchedule
 		ReportInterval time.Duration
-		MetricsPort    int
+		// MetricsPort of the prometheus endpoint (default is any free port, the bound one is logged and stored in Stress.MetricsPort)
+		MetricsPort int
+		// LatencyBuckets overrides layout of exported prometheus latency histogram (in seconds)
+		// Percentiles in the report are computed from the high-resolution LatencyHistogram and don't depend on it
+		LatencyBuckets []float64
+		// ResultsFile is a path of the time-series results file (CSV for .csv extension, JSONL otherwise)
+		ResultsFile     string
+		ResultsInterval time.Duration
+		// HtmlReportFile is a path of the self-contained html report generated after the run
+		HtmlReportFile string
+		// Baseline configures saving result of the run and comparing it with the result of the previous run
+		Baseline BaselineOptions
+		// Saturation configures detection of intervals when load generator itself was saturated
+		Saturation SaturationOptions
+		// Export configures pushing of stress metrics to the Pushgateway or remote-write endpoint
+		Export ExportOptions
+		// Otlp configures export of stress metrics and per-request spans with OTLP/HTTP protocol
+		Otlp OtlpOptions
+		// Dashboard replaces periodic stat logs with live terminal dashboard when stdout is a terminal
+		Dashboard bool
+		// Report selects reporters invoked every ReportInterval and on completion of the run
+		Report ReportOptions
+		// Thresholds defines limits which the whole run must satisfy
+		Thresholds ThresholdOptions
+		// Profile selects preset or custom profile which derives schedule of the run from the base Schedule
+		Profile string
+		// Profiles are custom profiles of the test (they replace presets with the same name)
+		Profiles []Profile
+		// Preflight executes the stress function few times sequentially before the load starts
+		Preflight PreflightOptions
+		// DryRun prints the planned schedule without calling the stress function
+		DryRun bool
+		// Setup is called once before the load starts, its output is available to all requests as RequestContext.State
+		Setup func(ctx context.Context) (any, error)
+		// Teardown is called with the output of Setup after the run, even if it was cancelled or aborted
+		Teardown func(ctx context.Context, state any) error
+		// TeardownTimeout limits duration of the Teardown (default 1m)
+		TeardownTimeout time.Duration
+		// Events configures structured log of the run events
+		Events EventOptions
+		// DrainTimeout limits graceful shutdown after the first SIGINT/SIGTERM: drain of in-flight requests, reports and teardown
+		// (default is DefaultDrainTimeout plus worker and teardown timeouts); second signal terminates the process immediately
+		DrainTimeout time.Duration
+		// Grafana configures posting of run events as annotations to the Grafana
+		Grafana GrafanaOptions
 	}
 )
 
 func NewGoStress(t *testing.T, options GoStressOptions, f StressFn) (Stress, func()) {
-	registerMetrics(t.Name())
 	logger := zaptest.NewLogger(t).Sugar()
+	overrides, err := LoadOverrides()
+	if err != nil {
+		t.Fatalf("unable to load gostress overrides: %v", err)
+	}
+	stress, shutdown, err := newStress(t.Name(), options, overrides, logger, t, f)
+	if err != nil {
+		shutdown()
+		t.Fatalf("invalid gostress options: %v", err)
+	}
+	return stress, shutdown
+}
+
+// NewStress creates stress outside of the go test, failures of the run are reported to the given TestingT
+// Invalid options (unknown profile, empty schedule) are reported as well, and the run of such stress is aborted without load
+func NewStress(name string, options GoStressOptions, logger *zap.SugaredLogger, t TestingT, f StressFn) (Stress, func()) {
+	stress, shutdown, err := newStress(name, options, Overrides{}, logger, t, f)
+	if err != nil {
+		t.Errorf("invalid gostress options: %v", err)
+	}
+	return stress, shutdown
+}
+
+// resolveOptions applies profile first and overrides after it, so explicit schedule and multipliers are not discarded by the profile
+func resolveOptions(options GoStressOptions, overrides Overrides, logger *zap.SugaredLogger) (GoStressOptions, prometheus.Labels, error) {
+	labels := prometheus.Labels{}
+	if overrides.Profile != "" {
+		options.Profile = overrides.Profile
+	}
+	if options.Profile != "" {
+		profile, err := ResolveProfile(options.Profile, options.Profiles)
+		if err != nil {
+			return options, labels, fmt.Errorf("unable to apply profile: %w", err)
+		}
+		options = profile.Apply(options)
+		labels["gostress_profile"] = profile.Name
+		logger.Infof("applied profile %v, schedule: %v", profile.Name, options.Schedule)
+	}
+	if !overrides.Empty() {
+		options = overrides.Apply(options)
+		logger.Infof("applied overrides %v, schedule: %v", overrides.Args(), options.Schedule)
+	}
+	if len(options.Schedule) == 0 && options.Profile != "" {
+		return options, labels, fmt.Errorf("schedule is empty: profile %v is derived from the base schedule, which is not set", options.Profile)
+	}
+	if len(options.Schedule) == 0 {
+		return options, labels, fmt.Errorf("schedule is empty")
+	}
+	return options, labels, nil
+}
 
-	logger.Infof("initialized gostress instance for test %v with timeout %v", t.Name(), options.WorkerTimeout)
+// newStress returns stress even for invalid options, so the caller decides how to fail; run of such stress is aborted
+func newStress(name string, options GoStressOptions, overrides Overrides, logger *zap.SugaredLogger, t TestingT, f StressFn) (Stress, func(), error) {
+	options, labels, invalid := resolveOptions(options, overrides, logger)
+	metrics := NewLabeledMetrics(name, options.LatencyBuckets, labels)
+	for _, handler := range options.Events.Handlers {
+		metrics.Events.Subscribe(handler)
+	}
+	var grafana *GrafanaAnnotator
+	if options.Grafana.Url != "" {
+		grafana = NewGrafanaAnnotator(name, options.Grafana)
+		metrics.Events.Subscribe(grafana.Handle)
+	}
+
+	logger.Infof("initialized gostress instance for %v with timeout %v", name, options.WorkerTimeout)
 
 	port := options.MetricsPort
 	if port == 0 {
-		port = 3000
+		// pod advertises fixed port to prometheus, otherwise any free port is used, so parallel runs don't collide
+		port, _ = strconv.Atoi(os.Getenv(GoStressMetricsPortEnv))
+	}
+	server := &http.Server{Handler: promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{})}
+	if listener, err := net.Listen("tcp", fmt.Sprintf(":%v", port)); err != nil {
+		logger.Errorf("unable to serve prometheus metrics: %v", err)
+	} else {
+		port = listener.Addr().(*net.TCPAddr).Port
+		logger.Infof("serving prometheus metrics at %v", listener.Addr())
+		go func() {
+			if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Errorf("http server failed: %v", err)
+			}
+		}()
+	}
+	reportInterval := options.ReportInterval
+	if reportInterval == 0 {
+		reportInterval = 10 * time.Second
+	}
+	var tracer *OtlpTracer
+	if options.Otlp.Endpoint != "" && options.Otlp.Traces {
+		tracer = NewOtlpTracer(options.Otlp)
+		f = tracer.Wrap(name, f)
+	}
+	resultsInterval := options.ResultsInterval
+	if resultsInterval == 0 {
+		resultsInterval = time.Second
 	}
-	server := &http.Server{Addr: fmt.Sprintf(":%v", port), Handler: promhttp.Handler()}
-	go func() {
-		if err := server.ListenAndServe(); err != nil {
-			logger.Errorf("http server failed: %v", err)
-		}
-	}()
 	stress := Stress{
-		Name:           t.Name(),
-		Nonce:          uuid.Must(uuid.NewUUID()).String()[:8],
-		Workers:        NewWorkerPool(options.WorkerTimeout, logger, f),
-		Runner:         NewRunner(),
-		Schedule:       options.Schedule,
-		ReportInterval: options.ReportInterval,
-		Logger:         logger,
-		MetricsPort:    port,
+		Name:            name,
+		Nonce:           uuid.Must(uuid.NewUUID()).String()[:8],
+		Workers:         NewWorkerPool(options.WorkerTimeout, metrics, logger, f),
+		Runner:          NewRunner(metrics),
+		Metrics:         metrics,
+		Schedule:        options.Schedule,
+		ReportInterval:  reportInterval,
+		Logger:          logger,
+		MetricsPort:     port,
+		ResultsFile:     options.ResultsFile,
+		ResultsInterval: resultsInterval,
+		HtmlReportFile:  options.HtmlReportFile,
+		Options:         options,
+		T:               t,
+		Tracer:          tracer,
+		Grafana:         grafana,
+		Overrides:       overrides,
+		invalid:         invalid,
 	}
 	return stress, func() {
 		logger.Infof("shutdown gostress")
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 		_ = server.Shutdown(ctx)
+	}, invalid
+}
+
+// invalidResult is a result of the stress with invalid options, failure is already reported by the constructor
+func (s *Stress) invalidResult() Result {
+	return Result{Name: s.Name, Profile: s.Options.Profile, Aborted: true, AbortReason: s.invalid.Error()}
+}
+
+// RunLocal runs the stress in the current process: setup, preflight, load and teardown
+// In k8s the launcher doesn't call RunLocal, so setup and teardown are executed only inside the pod
+func (s *Stress) RunLocal(ctx context.Context) (result Result) {
+	if s.invalid != nil {
+		return s.invalidResult()
+	}
+	// deferred first, so signals are handled until reports are flushed and teardown is finished
+	ctx, stopShutdown := NotifyShutdown(ctx, s.drainTimeout(), s.Logger)
+	defer stopShutdown()
+	events, startTime := s.Metrics.Events, time.Now()
+	s.openEventLog()
+	if s.Grafana != nil {
+		// deferred before the run_end emission, so annotations are posted after it
+		defer s.Grafana.Start(s.Logger)()
+	}
+	events.Emit(EventRunStart, fmt.Sprintf("%v started", s.Name), "name", s.Name, "profile", s.Options.Profile)
+	defer func() {
+		events.Emit(EventRunEnd, fmt.Sprintf("%v finished", s.Name))
+		result.Events = events.Events(startTime, time.Now())
+		if err := events.Close(); err != nil {
+			s.Logger.Errorf("unable to close event log: %v", err)
+		}
+		if path := os.Getenv(GoStressResultFileEnv); path != "" {
+			if err := result.WriteFile(path); err != nil {
+				s.Logger.Errorf("unable to write result for the launcher: %v", err)
+			}
+		}
+	}()
+	if s.Options.DryRun {
+		s.Logger.Infof("dry run of %v, planned schedule:\n%v", s.Name, PlanSchedule(s.Schedule))
+		return Result{Name: s.Name, Profile: s.Options.Profile}
+	}
+	state, err := s.setup(ctx)
+	if err != nil {
+		events.Emit(EventAbort, err.Error())
+		s.T.Errorf("load is not started: %v", err)
+		return Result{Name: s.Name, Profile: s.Options.Profile, Aborted: true, AbortReason: err.Error(), SetupError: err.Error()}
+	}
+	// teardown is deferred, so it is executed on cancellation, abort and panic as well
+	defer func() {
+		if err := s.teardown(state); err != nil {
+			events.Emit(EventTeardown, err.Error())
+			s.T.Errorf("teardown failed: %v", err)
+			result.TeardownError = err.Error()
+		}
+	}()
+	s.Workers.SetState(state)
+	if s.Options.Preflight.Requests > 0 {
+		if result, ok := s.preflight(ctx, state); !ok {
+			return result
+		}
+	}
+	return s.runLoad(ctx)
+}
+
+func (s *Stress) drainTimeout() time.Duration {
+	if s.Options.DrainTimeout > 0 {
+		return s.Options.DrainTimeout
 	}
+	timeout := DefaultDrainTimeout + 2*s.Workers.Timeout
+	if s.Options.Teardown != nil {
+		timeout += s.teardownTimeout()
+	}
+	return timeout
 }
 
-func (s *Stress) RunLocal(ctx context.Context) {
-	shutdown := Monitor(s.Name, s.ReportInterval, s.Logger)
-	defer shutdown()
-	err := s.Runner.RunSchedule(ctx, s.Schedule, s.Workers, s.Logger)
+func (s *Stress) openEventLog() {
+	if s.Options.Events.File == "" {
+		return
+	}
+	if err := s.Metrics.Events.WriteFile(s.Options.Events.File); err != nil {
+		s.Logger.Errorf("unable to write event log: %v", err)
+	}
+}
+
+func (s *Stress) setup(ctx context.Context) (any, error) {
+	if s.Options.Setup == nil {
+		return nil, nil
+	}
+	s.Logger.Infof("run setup of %v", s.Name)
+	state, err := s.Options.Setup(ctx)
 	if err != nil {
-		s.Logger.Error("run schedule failed with error: %v", err)
+		return nil, fmt.Errorf("setup failed: %w", err)
+	}
+	s.Metrics.Events.Emit(EventSetup, "setup finished")
+	return state, nil
+}
+
+func (s *Stress) teardownTimeout() time.Duration {
+	if s.Options.TeardownTimeout > 0 {
+		return s.Options.TeardownTimeout
+	}
+	return time.Minute
+}
+
+func (s *Stress) teardown(state any) error {
+	if s.Options.Teardown == nil {
+		return nil
+	}
+	// run context may be already cancelled at this point, so teardown gets its own one
+	ctx, cancel := context.WithTimeout(context.Background(), s.teardownTimeout())
+	defer cancel()
+	s.Logger.Infof("run teardown of %v", s.Name)
+	if err := s.Options.Teardown(ctx, state); err != nil {
+		return fmt.Errorf("teardown failed: %w", err)
+	}
+	s.Metrics.Events.Emit(EventTeardown, "teardown finished")
+	return nil
+}
+
+func (s *Stress) runLoad(ctx context.Context) Result {
+	finishReports := s.monitor()
+	buffer := &ResultsBuffer{}
+	writers := []ResultsWriter{buffer}
+	if s.ResultsFile != "" {
+		writer, err := NewResultsWriter(s.ResultsFile)
+		if err != nil {
+			s.Logger.Errorf("unable to record results: %v", err)
+		} else {
+			s.Logger.Infof("recording results to %v every %v", s.ResultsFile, s.ResultsInterval)
+			writers = append(writers, writer)
+		}
+	}
+	stopSaturation := s.Metrics.Saturation.Start(s.Options.Saturation)
+	stopExporters := s.startExporters()
+	stopTracer := func() {}
+	if s.Tracer != nil {
+		stopTracer = s.Tracer.Start(s.Logger)
+	}
+	stopRecording := RecordResults(s.ResultsInterval, s.Metrics, s.Logger, writers...)
+	start := s.Metrics.Snapshot()
+	stages, err := s.Runner.RunSchedule(ctx, s.Schedule, s.Workers, s.Logger)
+	// requests in flight are finished (or timed out) before the final snapshot, so they are counted in the result
+	if !s.Workers.Drain(2 * s.Workers.Timeout) {
+		s.Logger.Warnf("some workers didn't finish in-flight requests within %v", 2*s.Workers.Timeout)
+	}
+	end := s.Metrics.Snapshot()
+	for i := range stages {
+		// requests are tagged with the stage at the trigger time, so latency of requests finished after the stage end is counted too
+		stages[i].Latency, stages[i].Histograms = summarizeHistograms(s.Metrics.Latency.StageSnapshot(stages[i].Index))
+		stages[i].Phases, _ = summarizeHistograms(s.Metrics.Phases.StageSnapshot(stages[i].Index))
+	}
+	result := Result{
+		Name:      s.Name,
+		Profile:   s.Options.Profile,
+		StartTime: start.Time,
+		EndTime:   end.Time,
+		Duration:  end.Time.Sub(start.Time),
+		Total:     end.StageResult(start),
+		Stages:    stages,
+	}
+	if err != nil {
+		s.Logger.Errorf("run schedule failed with error: %v", err)
+		result.Aborted, result.AbortReason = true, err.Error()
 	} else {
 		s.Logger.Infof("run schedule finished successfully")
 	}
+	stopSaturation()
+	stopRecording()
+	stopExporters()
+	stopTracer()
+	s.checkSaturation(&result)
+	s.checkThresholds(result)
+	result.Events = s.Metrics.Events.Events(start.Time, time.Now())
+	finishReports(result)
+	if s.HtmlReportFile != "" {
+		s.writeHtmlReport(buffer.Rows(), result.Events)
+	}
+	s.checkBaseline(result)
+	return result
+}
+
+func (s *Stress) preflight(ctx context.Context, state any) (Result, bool) {
+	timeout := s.Options.Preflight.Timeout
+	if timeout == 0 {
+		timeout = s.Options.WorkerTimeout
+	}
+	s.Logger.Infof("run %v preflight requests", s.Options.Preflight.Requests)
+	startTime := time.Now()
+	err := RunPreflight(ctx, s.Options.Preflight.Requests, timeout, s.Logger, func(ctx RequestContext) error {
+		ctx.State = state
+		return s.Workers.F(ctx)
+	})
+	if err == nil {
+		s.Metrics.Events.Emit(EventPreflight, fmt.Sprintf("%v preflight requests succeeded", s.Options.Preflight.Requests))
+		return Result{}, true
+	}
+	s.Metrics.Events.Emit(EventAbort, err.Error())
+	result := Result{Name: s.Name, Profile: s.Options.Profile, StartTime: startTime, EndTime: time.Now(), Aborted: true, AbortReason: err.Error()}
+	result.Duration = result.EndTime.Sub(result.StartTime)
+	s.T.Errorf("preflight failed, load is not started: %v", err)
+	return result, false
+}
+
+func (s *Stress) reporters() []Reporter {
+	reporters := make([]Reporter, 0, len(s.Options.Report.Formats)+len(s.Options.Report.Reporters))
+	formats := s.Options.Report.Formats
+	if len(formats) == 0 {
+		formats = []string{"text"}
+	}
+	for _, format := range formats {
+		reporter, err := NewReporter(format, s.Logger)
+		if err != nil {
+			s.Logger.Errorf("unable to create reporter: %v", err)
+			continue
+		}
+		reporters = append(reporters, reporter)
+	}
+	return append(reporters, s.Options.Report.Reporters...)
+}
+
+func (s *Stress) monitor() func(result Result) {
+	reporters := s.reporters()
+	dashboard := s.Options.Dashboard && IsTerminal(os.Stdout)
+	if s.Options.Dashboard && !dashboard {
+		s.Logger.Infof("stdout is not a terminal, fallback to the log output instead of dashboard")
+	}
+	stopDashboard, logger := func() {}, s.Logger
+	if dashboard {
+		for _, reporter := range reporters {
+			if text, ok := reporter.(*TextReporter); ok {
+				text.FinalOnly = true
+			}
+		}
+		stopDashboard = RunDashboard(NewDashboard(s.Name, s.Schedule, s.Metrics, os.Stdout))
+		// dashboard shows expected and achieved rps itself, so pacing warnings shouldn't break its rendering
+		logger = zap.NewNop().Sugar()
+	}
+	stopMonitor := Monitor(s.Name, s.Metrics, logger)
+	stopReporters := RunReporters(s.Name, s.Options.Profile, s.ReportInterval, s.Metrics, reporters, s.Logger)
+	return func(result Result) {
+		stopMonitor()
+		stopDashboard()
+		stopReporters(result)
+	}
+}
+
+func (s *Stress) startExporters() func() {
+	options := s.Options.Export
+	exporters := make([]Exporter, 0)
+	grouping := map[string]string{"instance": s.Nonce}
+	if options.PushgatewayUrl != "" {
+		exporters = append(exporters, NewPushgatewayExporter(options.PushgatewayUrl, "gostress", s.Metrics.Registry, grouping))
+	}
+	if options.RemoteWriteUrl != "" {
+		exporters = append(exporters, NewRemoteWriteExporter(options.RemoteWriteUrl, s.Metrics.Registry, grouping))
+	}
+	if s.Options.Otlp.Endpoint != "" {
+		exporters = append(exporters, NewOtlpMetricsExporter(s.Options.Otlp, s.Metrics.Registry))
+	}
+	if len(exporters) == 0 {
+		return func() {}
+	}
+	interval := options.Interval
+	if interval == 0 {
+		interval = 10 * time.Second
+	}
+	s.Logger.Infof("exporting metrics every %v to %v exporters", interval, len(exporters))
+	return RunExporters(interval, exporters, s.Logger)
+}
+
+func (s *Stress) checkSaturation(result *Result) {
+	samples := s.Metrics.Saturation.Samples(result.StartTime, result.EndTime)
+	result.SaturatedFraction = SaturatedFraction(samples)
+	result.SaturationReasons = SaturationReasons(samples)
+	if result.SaturatedFraction == 0 {
+		return
+	}
+	s.Logger.Warnf(
+		"load generator was saturated during %.1f%% of the run: %v",
+		100*result.SaturatedFraction, strings.Join(result.SaturationReasons, ", "),
+	)
+	failFraction := s.Options.Saturation.FailFraction
+	if failFraction > 0 && result.SaturatedFraction > failFraction {
+		result.Invalid = true
+		result.InvalidReason = fmt.Sprintf("load generator was saturated during %.1f%% of the run (threshold %.1f%%)", 100*result.SaturatedFraction, 100*failFraction)
+		s.Metrics.Events.Emit(EventThresholdBreach, result.InvalidReason)
+		s.T.Errorf("stress run is invalid: %v", result.InvalidReason)
+	}
+}
+
+func (s *Stress) checkThresholds(result Result) {
+	for _, violation := range CheckThresholds(result, s.Options.Thresholds) {
+		s.Metrics.Events.Emit(EventThresholdBreach, violation)
+		s.T.Errorf("threshold violated: %v", violation)
+	}
+}
+
+func (s *Stress) checkBaseline(result Result) {
+	options := s.Options.Baseline
+	if (options.SaveFile != "" || options.File != "") && (result.Aborted || result.Invalid) {
+		// partial or saturated run would poison the baseline and produce false regressions
+		s.Logger.Warnf("baseline is neither saved nor compared, because the run was aborted or invalid")
+		return
+	}
+	if options.SaveFile != "" {
+		if err := result.WriteFile(options.SaveFile); err != nil {
+			s.Logger.Errorf("unable to save baseline: %v", err)
+		} else {
+			s.Logger.Infof("baseline saved to %v", options.SaveFile)
+		}
+	}
+	if options.File == "" {
+		return
+	}
+	baseline, err := ReadResult(options.File)
+	if err != nil {
+		s.T.Errorf("unable to load baseline: %v", err)
+		return
+	}
+	regressions := CompareResults(baseline, result, options)
+	if len(regressions) == 0 {
+		s.Logger.Infof("no regressions found against baseline %v", options.File)
+		return
+	}
+	for _, regression := range regressions {
+		s.Metrics.Events.Emit(EventThresholdBreach, fmt.Sprintf("regression against baseline: %v", regression))
+		s.T.Errorf("regression against baseline %v: %v", options.File, regression)
+	}
+}
+
+func (s *Stress) writeHtmlReport(rows []ResultsRow, events []Event) {
+	summary, err := PrintStat(s.Metrics)
+	if err != nil {
+		s.Logger.Errorf("unable to gather metrics for html report: %v", err)
+	}
+	report := HtmlReport{
+		Name:     s.Name,
+		Options:  DescribeOptions(s.Options),
+		Schedule: s.Schedule,
+		Rows:     rows,
+		Summary:  summary,
+		Events:   events,
+	}
+	if err := report.WriteFile(s.HtmlReportFile); err != nil {
+		s.Logger.Errorf("unable to write html report: %v", err)
+	} else {
+		s.Logger.Infof("html report written to %v", s.HtmlReportFile)
+	}
 }
@@ -0,0 +1,42 @@
+package gostress
+
+import (
+	"fmt"
+	"time"
+)
+
+// ThresholdOptions defines limits which the whole run must satisfy; violations fail the test (or the cli run)
+type ThresholdOptions struct {
+	// FailOnErrors fails the run on any failed request
+	FailOnErrors bool
+	// MaxErrorRate is a maximum fraction of failed requests
+	MaxErrorRate float64
+	// MaxP50 and MaxP99 are maximum percentiles of the successful requests latency
+	MaxP50 time.Duration
+	MaxP99 time.Duration
+}
+
+// CheckThresholds returns description of every threshold violated by the result
+func CheckThresholds(result Result, options ThresholdOptions) []string {
+	violations := make([]string, 0)
+	total := result.Total
+	if options.FailOnErrors && total.Errors > 0 {
+		violations = append(violations, fmt.Sprintf("%v requests failed", total.Errors))
+	}
+	if options.MaxErrorRate > 0 && total.Sent > 0 {
+		if errorRate := float64(total.Errors) / float64(total.Sent); errorRate > options.MaxErrorRate {
+			violations = append(violations, fmt.Sprintf("error rate %.2f%% > %.2f%%", 100*errorRate, 100*options.MaxErrorRate))
+		}
+	}
+	latency, ok := total.Latency["success"]
+	if !ok {
+		return violations
+	}
+	if options.MaxP50 > 0 && latency.P50 > options.MaxP50 {
+		violations = append(violations, fmt.Sprintf("p50 latency %v > %v", roundLatency(latency.P50), options.MaxP50))
+	}
+	if options.MaxP99 > 0 && latency.P99 > options.MaxP99 {
+		violations = append(violations, fmt.Sprintf("p99 latency %v > %v", roundLatency(latency.P99), options.MaxP99))
+	}
+	return violations
+}
@@ -0,0 +1,21 @@
+package gostress
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestCheckThresholds(t *testing.T) {
+	result := Result{Total: StageResult{
+		Sent:    100,
+		Errors:  5,
+		Latency: map[string]LatencySummary{"success": {Count: 95, P50: 10 * time.Millisecond, P99: 200 * time.Millisecond}},
+	}}
+	assert.Empty(t, CheckThresholds(result, ThresholdOptions{}))
+	assert.Empty(t, CheckThresholds(result, ThresholdOptions{MaxErrorRate: 0.1, MaxP50: 20 * time.Millisecond, MaxP99: time.Second}))
+	assert.Equal(t,
+		[]string{"error rate 5.00% > 1.00%", "p99 latency 200ms > 100ms"},
+		CheckThresholds(result, ThresholdOptions{MaxErrorRate: 0.01, MaxP50: 20 * time.Millisecond, MaxP99: 100 * time.Millisecond}),
+	)
+}
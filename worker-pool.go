@@ -1,7 +1,9 @@
 package gostress
 
 import (
+	"fmt"
 	"go.uber.org/zap"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -9,22 +11,26 @@ import (
 type (
 	StressFn   func(ctx RequestContext) error
 	WorkerPool struct {
-		F        StressFn
+		F StressFn
+		// State is an output of the setup, workers capture it when spawned, so workers left from the previous run keep their own state
+		State    any
 		Lock     sync.Mutex
 		Work     chan Id
 		Workers  []*Worker
 		WorkerId Id
 		Timeout  time.Duration
+		Metrics  *Metrics
 		Logger   *zap.SugaredLogger
 	}
 )
 
-func NewWorkerPool(workerTimeout time.Duration, logger *zap.SugaredLogger, f StressFn) *WorkerPool {
+func NewWorkerPool(workerTimeout time.Duration, metrics *Metrics, logger *zap.SugaredLogger, f StressFn) *WorkerPool {
 	return &WorkerPool{
 		F:       f,
 		Work:    make(chan Id),
 		Workers: make([]*Worker, 0),
 		Timeout: workerTimeout,
+		Metrics: metrics,
 		Logger:  logger,
 	}
 }
@@ -38,16 +44,24 @@ func (p *WorkerPool) Kill() {
 	p.Workers = p.Workers[:len(p.Workers)-1]
 }
 
+// Size returns current number of workers, it is safe to call concurrently with Adjust
+func (p *WorkerPool) Size() int {
+	p.Lock.Lock()
+	defer p.Lock.Unlock()
+	return len(p.Workers)
+}
+
 func (p *WorkerPool) Adjust(size int) {
-	if len(p.Workers) == size {
-		return
-	}
 	p.Lock.Lock()
 	defer p.Lock.Unlock()
 	if len(p.Workers) == size {
 		return
 	}
 	p.Logger.Infof("adjusting workers pool: current=%v, target=%v", len(p.Workers), size)
+	p.Metrics.Events.Emit(
+		EventPoolResize, fmt.Sprintf("workers pool resized from %v to %v", len(p.Workers), size),
+		"from", strconv.Itoa(len(p.Workers)), "to", strconv.Itoa(size),
+	)
 	for len(p.Workers) < size {
 		p.Spawn()
 	}
@@ -56,9 +70,37 @@ func (p *WorkerPool) Adjust(size int) {
 	}
 }
 
+// Drain stops all workers and waits until they finish in-flight requests; returns false if some workers didn't finish within timeout
+func (p *WorkerPool) Drain(timeout time.Duration) bool {
+	p.Lock.Lock()
+	workers := p.Workers
+	for len(p.Workers) > 0 {
+		p.Kill()
+	}
+	p.Lock.Unlock()
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for _, worker := range workers {
+		select {
+		case <-worker.Finished:
+		case <-deadline.C:
+			return false
+		}
+	}
+	return true
+}
+
+// SetState sets state passed to the requests of the workers spawned after the call
+func (p *WorkerPool) SetState(state any) {
+	p.Lock.Lock()
+	defer p.Lock.Unlock()
+	p.State = state
+}
+
 func (p *WorkerPool) Spawn() {
 	w := NewWorker(p.WorkerId)
+	w.State = p.State
 	p.WorkerId++
-	go func() { w.Run(p.Work, p.Timeout, p.Logger, p.F) }()
+	go func() { w.Run(p.Work, p.Timeout, p.Metrics, p.Logger, p.F) }()
 	p.Workers = append(p.Workers, w)
 }
@@ -8,6 +8,8 @@ import (
 
 type Worker struct {
 	WorkerId Id
+	// State is passed to every request as RequestContext.State
+	State    any
 	Shutdown chan struct{}
 	Finished chan struct{}
 }
@@ -23,6 +25,7 @@ func NewWorker(id Id) *Worker {
 func (w *Worker) Run(
 	work <-chan Id,
 	timeout time.Duration,
+	metrics *Metrics,
 	logger *zap.SugaredLogger,
 	f StressFn,
 ) {
@@ -31,21 +34,12 @@ work:
 	for {
 		select {
 		case id := <-work:
+			stage := metrics.Stage()
 			timer := time.NewTimer(2 * timeout)
 			finish := make(chan struct{}, 1)
 			go func() {
-				ctx, cancel := context.WithTimeout(context.Background(), timeout)
-				defer cancel()
-				startTime := time.Now()
-				err := f(RequestContext{Ctx: ctx, Id: id, Logger: logger.With(zap.Int64("worker", int64(w.WorkerId)))})
+				executeRequest(id, w.WorkerId, stage, w.State, timeout, metrics, logger, f)
 				finish <- struct{}{}
-				status := "success"
-				if err != nil {
-					status = "error"
-					ErrorsCounter.Inc()
-					logger.Errorf("worker[%v]: request finished with error: %v", w.WorkerId, err)
-				}
-				RequestLatency.WithLabelValues(status).Observe(time.Since(startTime).Seconds())
 			}()
 			select {
 			case <-finish:
@@ -62,3 +56,27 @@ work:
 	logger.Infof("worker[%v]: finished", w.WorkerId)
 	w.Finished <- struct{}{}
 }
+
+// executeRequest calls f with the request timeout and records its latency and error
+func executeRequest(id, worker Id, stage Stage, state any, timeout time.Duration, metrics *Metrics, logger *zap.SugaredLogger, f StressFn) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	startTime := time.Now()
+	err := f(RequestContext{
+		Ctx:     ctx,
+		Id:      id,
+		Worker:  worker,
+		Logger:  logger.With(zap.Int64("worker", int64(worker))),
+		State:   state,
+		metrics: metrics,
+		stage:   stage,
+	})
+	status := "success"
+	if err != nil {
+		status = "error"
+		metrics.ErrorsCounter.Inc()
+		metrics.ErrorClasses.Record(err)
+		logger.Errorf("worker[%v]: request finished with error: %v", worker, err)
+	}
+	metrics.ObserveLatency(stage, status, time.Since(startTime))
+}
@@ -12,7 +12,7 @@ func TestSimple(t *testing.T) {
 	w := NewWorker(0)
 	called := int32(0)
 	work := make(chan Id)
-	go w.Run(work, 1*time.Second, zaptest.NewLogger(t).Sugar(), func(ctx RequestContext) error {
+	go w.Run(work, 1*time.Second, NewMetrics(t.Name(), nil), zaptest.NewLogger(t).Sugar(), func(ctx RequestContext) error {
 		atomic.AddInt32(&called, 1)
 		return nil
 	})
@@ -28,7 +28,7 @@ func TestTimeout(t *testing.T) {
 	w := NewWorker(0)
 	called := int32(0)
 	work := make(chan Id)
-	go w.Run(work, 1*time.Second, zaptest.NewLogger(t).Sugar(), func(ctx RequestContext) error {
+	go w.Run(work, 1*time.Second, NewMetrics(t.Name(), nil), zaptest.NewLogger(t).Sugar(), func(ctx RequestContext) error {
 		atomic.AddInt32(&called, 1)
 		if ctx.Id == 0 {
 			time.Sleep(1 * time.Minute)
@@ -42,3 +42,25 @@ func TestTimeout(t *testing.T) {
 	<-w.Finished
 	assert.Equal(t, atomic.LoadInt32(&called), int32(10))
 }
+
+func TestWorkerPoolState(t *testing.T) {
+	states := make(chan any, 1)
+	pool := NewWorkerPool(time.Second, NewMetrics(t.Name(), nil), zaptest.NewLogger(t).Sugar(), func(ctx RequestContext) error {
+		states <- ctx.State
+		return nil
+	})
+	pool.SetState("first")
+	pool.Adjust(1)
+	pool.Work <- 0
+	assert.Equal(t, "first", <-states)
+
+	// workers of the previous run keep their state, new ones get the current
+	pool.SetState("second")
+	pool.Work <- 1
+	assert.Equal(t, "first", <-states)
+	assert.True(t, pool.Drain(time.Second))
+	pool.Adjust(1)
+	pool.Work <- 2
+	assert.Equal(t, "second", <-states)
+	assert.True(t, pool.Drain(time.Second))
+}